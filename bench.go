@@ -0,0 +1,78 @@
+package spi
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BenchResult reports the performance measured by Benchmark.
+type BenchResult struct {
+	Size       int
+	Iterations int
+
+	// Elapsed is the total time taken by the transfers.
+	Elapsed time.Duration
+
+	// Throughput is the number of bytes transferred per second,
+	// counting each byte once although it is both sent and received.
+	Throughput float64
+
+	// Latencies of individual transfers.
+	Min time.Duration
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Benchmark performs the given number of full-duplex transfers of size bytes
+// using the current device configuration, and reports their throughput and latency.
+// Zeros are transmitted, and the device configuration is not changed.
+// Transfers larger than MaxTransferSize will fail (see TransferLarge).
+func (dev *Device) Benchmark(size, iterations int) (BenchResult, error) {
+	if size <= 0 {
+		return BenchResult{}, fmt.Errorf("invalid benchmark size (%d)", size)
+	}
+	if iterations <= 0 {
+		return BenchResult{}, fmt.Errorf("invalid benchmark iterations (%d)", iterations)
+	}
+	snd := make([]byte, size)
+	rcv := make([]byte, size)
+	latency := make([]time.Duration, iterations)
+	var elapsed time.Duration
+	for i := range latency {
+		start := time.Now()
+		err := dev.Transfer(snd, rcv)
+		latency[i] = time.Since(start)
+		if err != nil {
+			return BenchResult{}, err
+		}
+		elapsed += latency[i]
+	}
+	sort.Slice(latency, func(i, j int) bool { return latency[i] < latency[j] })
+	r := BenchResult{
+		Size:       size,
+		Iterations: iterations,
+		Elapsed:    elapsed,
+		Min:        latency[0],
+		P50:        percentile(latency, 50),
+		P90:        percentile(latency, 90),
+		P99:        percentile(latency, 99),
+		Max:        latency[iterations-1],
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(size) * float64(iterations) / elapsed.Seconds()
+	}
+	return r, nil
+}
+
+// percentile returns the p'th percentile of the sorted durations d,
+// using the nearest-rank method.
+func percentile(d []time.Duration, p int) time.Duration {
+	rank := (p*len(d) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return d[rank-1]
+}
@@ -0,0 +1,61 @@
+package spi
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenBus opens the SPI device for the given bus and chip select numbers
+// (/dev/spidevBUS.CS) at the specified speed (in Hertz).
+// It returns an error wrapping ErrDeviceNotFound if there is no such device.
+func OpenBus(bus, cs, speed int) (*Device, error) {
+	return Open(busPath(bus, cs), speed, NoCustomCS)
+}
+
+func busPath(bus, cs int) string {
+	return fmt.Sprintf("/dev/spidev%d.%d", bus, cs)
+}
+
+// Devices returns the paths of the SPI devices present on the system,
+// sorted by bus and chip select number.
+func Devices() ([]string, error) {
+	paths, err := filepath.Glob("/dev/spidev*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		bi, ci, erri := DeviceInfo(paths[i])
+		bj, cj, errj := DeviceInfo(paths[j])
+		switch {
+		case erri != nil || errj != nil:
+			return paths[i] < paths[j]
+		case bi != bj:
+			return bi < bj
+		default:
+			return ci < cj
+		}
+	})
+	return paths, nil
+}
+
+// DeviceInfo returns the bus and chip select numbers
+// of an SPI device path of the form /dev/spidevBUS.CS.
+func DeviceInfo(path string) (bus, cs int, err error) {
+	name := filepath.Base(path)
+	nums := strings.Split(strings.TrimPrefix(name, "spidev"), ".")
+	if name == nums[0] || len(nums) != 2 {
+		return 0, 0, fmt.Errorf("%s: not an spidev device name", path)
+	}
+	bus, err = strconv.Atoi(nums[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: invalid bus number: %w", path, err)
+	}
+	cs, err = strconv.Atoi(nums[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: invalid chip select number: %w", path, err)
+	}
+	return bus, cs, nil
+}
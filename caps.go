@@ -0,0 +1,81 @@
+package spi
+
+// Caps describes the capabilities of an SPI controller,
+// as determined by Capabilities.
+type Caps struct {
+	// MaxSpeed is the device's maximum speed in Hertz, or 0 if unknown.
+	MaxSpeed int
+
+	// Each flag is true if the corresponding mode could be enabled.
+	// A false value means the mode was rejected or ignored,
+	// which may also happen if the probe itself failed.
+	TxDual    bool
+	TxQuad    bool
+	RxDual    bool
+	RxQuad    bool
+	ThreeWire bool
+	LSBFirst  bool
+	Loopback  bool
+}
+
+// Capabilities probes the controller by trying to enable
+// each mode flag in turn, and then restores the original mode.
+// The result is best-effort: drivers that accept a mode flag
+// without actually implementing it cannot be detected.
+func (dev *Device) Capabilities() (Caps, error) {
+	var caps Caps
+	if speed, err := dev.MaxSpeed(); err == nil {
+		caps.MaxSpeed = speed
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	var orig uint32
+	err := dev.syscallU32(spi_IOC_RD_MODE32, &orig)
+	if err != nil {
+		return caps, err
+	}
+	probes := []struct {
+		flag Mode
+		ok   *bool
+	}{
+		{TxDual, &caps.TxDual},
+		{TxQuad, &caps.TxQuad},
+		{RxDual, &caps.RxDual},
+		{RxQuad, &caps.RxQuad},
+		{ThreeWire, &caps.ThreeWire},
+		{LSBFirst, &caps.LSBFirst},
+		{Loopback, &caps.Loopback},
+	}
+	base := Mode(orig) &^ (TxDual | TxQuad | RxDual | RxQuad | ThreeWire)
+	for _, p := range probes {
+		mode := uint32(base | p.flag)
+		if dev.syscallU32(spi_IOC_WR_MODE32, &mode) != nil {
+			continue
+		}
+		var got uint32
+		if dev.syscallU32(spi_IOC_RD_MODE32, &got) == nil {
+			*p.ok = Mode(got)&p.flag != 0
+		}
+	}
+	return caps, dev.syscallU32(spi_IOC_WR_MODE32, &orig)
+}
+
+// SupportsLSBFirst reports whether the controller accepts LSB-first bit order,
+// by trying to enable it and then restoring the previous setting.
+func (dev *Device) SupportsLSBFirst() bool {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	var prev uint8
+	if dev.syscallU8(spi_IOC_RD_LSB_FIRST, &prev) != nil {
+		return false
+	}
+	if prev != 0 {
+		return true
+	}
+	b := uint8(1)
+	if dev.syscallU8(spi_IOC_WR_LSB_FIRST, &b) != nil {
+		return false
+	}
+	_ = dev.syscallU8(spi_IOC_WR_LSB_FIRST, &prev)
+	return true
+}
@@ -0,0 +1,103 @@
+package spi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// spidev rejects messages larger than this with EMSGSIZE.
+	bufsizFile    = "/sys/module/spidev/parameters/bufsiz"
+	defaultBufsiz = 4096
+)
+
+// TransferLarge performs an SPI transfer operation of any length,
+// splitting it into as many ioctls as the spidev buffer size requires.
+// Chip select is kept asserted between the pieces where possible:
+// a custom chip select is asserted once around the whole transfer,
+// and the hardware chip select is requested to remain asserted
+// (although not all controllers honor this).
+func (dev *Device) TransferLarge(snd, rcv []byte) error {
+	deadline := dev.transferDeadline()
+	if deadline.IsZero() {
+		return dev.transferLarge(context.Background(), snd, rcv)
+	}
+	return runDeadline(deadline, func(ctx context.Context) error {
+		return dev.transferLarge(ctx, snd, rcv)
+	})
+}
+
+// transferLarge is TransferLarge, but ctx takes the place of the transfer deadline.
+func (dev *Device) transferLarge(ctx context.Context, snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	if len(snd) == 0 {
+		return nil
+	}
+	size, err := dev.MaxTransferSize()
+	if err != nil {
+		return err
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = dev.checkWordSize(len(snd))
+	if err != nil {
+		return err
+	}
+	if dev.autoCS() {
+		dev.selectCS()
+		defer dev.deselectCS()
+	}
+	for start := 0; start < len(snd); start += size {
+		end := start + size
+		if end > len(snd) {
+			end = len(snd)
+		}
+		tr := dev.newTransfer(snd[start:end], rcv[start:end], end-start)
+		if end < len(snd) {
+			tr.cs_change = 1
+		}
+		err := dev.submit([]spi_ioc_transfer{tr})
+		runtime.KeepAlive(snd)
+		runtime.KeepAlive(rcv)
+		if err != nil {
+			return err
+		}
+	}
+	return dev.completed(snd, rcv)
+}
+
+// MaxTransferSize returns the maximum number of bytes that spidev
+// accepts in a single transfer, as given by its bufsiz parameter,
+// or 4096 if that is not available.
+func (dev *Device) MaxTransferSize() (int, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.maxTransfer != 0 {
+		return dev.maxTransfer, nil
+	}
+	data, err := ioutil.ReadFile(bufsizFile)
+	if os.IsNotExist(err) {
+		dev.maxTransfer = defaultBufsiz
+		return dev.maxTransfer, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: invalid value %q", bufsizFile, data)
+	}
+	dev.maxTransfer = n
+	return n, nil
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ecc1/spi"
 )
@@ -12,32 +17,155 @@ import (
 var (
 	device   = flag.String("d", "/dev/spidev5.1", "SPI `device`")
 	speed    = flag.Int("s", 1000000, "SPI `speed` (Hz)")
-	customCS = flag.Int("cs", 0, "use `GPIO#` as custom chip select")
+	customCS = flag.Int("cs", spi.NoCustomCS, "use `GPIO#` as custom chip select")
+	mode     = flag.Int("mode", -1, "set SPI `mode` (0 to 3)")
+	bits     = flag.Int("bits", 0, "set bits per word to `N`")
+	lsb      = flag.Bool("lsb", false, "transfer least significant bit first")
+	readLen  = flag.Int("read", 0, "after sending, receive `N` more bytes and print them separately")
+	repeat   = flag.Int("repeat", 1, "perform the transfer `N` times (0 means until interrupted)")
+	interval = flag.Duration("interval", 0, "wait `duration` between repeated transfers")
 )
 
+// envFlags gives the environment variables used as defaults for flags.
+var envFlags = []struct {
+	flag string
+	env  string
+}{
+	{"d", "SPI_DEVICE"},
+	{"s", "SPI_SPEED"},
+	{"mode", "SPI_MODE"},
+}
+
 func main() {
 	flag.Parse()
+	err := applyEnv(flag.CommandLine)
+	if err != nil {
+		log.Fatal(err)
+	}
 	var values []byte
 	for _, v := range flag.Args() {
-		b, err := strconv.ParseUint(v, 16, 8)
+		b, err := parseHex(v)
 		if err != nil {
 			log.Fatal(err)
 		}
-		values = append(values, byte(b))
+		values = append(values, b...)
 	}
 	dev, err := spi.Open(*device, *speed, *customCS)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer dev.Close()
-	if len(values)%2 == 1 {
+	err = configure(dev)
+	if err != nil {
+		dev.Close()
+		log.Fatal(err)
+	}
+	fmt.Println(dev)
+	if *readLen == 0 && len(values)%2 == 1 {
 		values = append(values, 0)
 	}
-	response := make([]byte, len(values))
-	fmt.Printf("send: % X\n", values)
-	err = dev.Transfer(values, response)
+	if *repeat != 1 {
+		stopOnInterrupt(dev)
+	}
+	rcv := make([]byte, len(values))
+	for i := 0; *repeat == 0 || i < *repeat; i++ {
+		if i != 0 {
+			time.Sleep(*interval)
+		}
+		fmt.Printf("send: % X\n", values)
+		if *readLen != 0 {
+			rcv, err = dev.WriteRead(values, *readLen)
+		} else {
+			err = dev.Transfer(values, rcv)
+		}
+		if err != nil {
+			dev.Close()
+			log.Fatal(err)
+		}
+		if *readLen != 0 {
+			fmt.Printf("read: % X\n", rcv)
+		} else {
+			fmt.Printf("recv: % X\n", rcv)
+		}
+	}
+}
+
+// stopOnInterrupt closes dev and exits when the program is interrupted,
+// whether it is waiting between transfers or in the middle of one.
+// A second interrupt ends the program immediately,
+// in case a transfer is stuck and dev cannot be closed.
+func stopOnInterrupt(dev *spi.Device) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		signal.Stop(interrupt)
+		dev.Close()
+		os.Exit(0)
+	}()
+}
+
+// applyEnv sets the flags in fs listed in envFlags from the environment,
+// unless they were given on the command line.
+func applyEnv(fs *flag.FlagSet) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, e := range envFlags {
+		v, ok := os.LookupEnv(e.env)
+		if !ok || given[e.flag] {
+			continue
+		}
+		err := fs.Set(e.flag, v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.env, err)
+		}
+	}
+	return nil
+}
+
+// configure applies the -mode, -bits, and -lsb flags to dev.
+func configure(dev *spi.Device) error {
+	if *mode != -1 {
+		if *mode < 0 || *mode > 3 {
+			return fmt.Errorf("invalid SPI mode %d", *mode)
+		}
+		err := dev.SetMode(spi.Mode(*mode))
+		if err != nil {
+			return err
+		}
+	}
+	if *bits != 0 {
+		err := dev.SetBitsPerWord(*bits)
+		if err != nil {
+			return err
+		}
+	}
+	if *lsb {
+		return dev.SetLSBFirst(true)
+	}
+	return nil
+}
+
+// parseHex parses an argument as one hex byte, such as "7" or "0x7F",
+// or as a string of hex bytes, such as "DEADBEEF".
+func parseHex(arg string) ([]byte, error) {
+	v := arg
+	if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+		v = v[2:]
+	}
+	if len(v) <= 2 {
+		b, err := strconv.ParseUint(v, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex byte %q", arg)
+		}
+		return []byte{byte(b)}, nil
+	}
+	if len(v)%2 != 0 {
+		return nil, fmt.Errorf("hex string %q has an odd number of digits", arg)
+	}
+	b, err := hex.DecodeString(v)
 	if err != nil {
-		log.Fatalf("%s: %v", *device, err)
+		return nil, fmt.Errorf("invalid hex string %q", arg)
 	}
-	fmt.Printf("recv: % X\n", response)
+	return b, nil
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+)
+
+func TestParseHex(t *testing.T) {
+	cases := []struct {
+		arg  string
+		want []byte // nil means an error is expected
+	}{
+		{"7", []byte{0x07}},
+		{"0x7F", []byte{0x7F}},
+		{"0X7f", []byte{0x7F}},
+		{"DEADBEEF", []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{"0xdeadbeef", []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{"0x", nil},
+		{"", nil},
+		{"ABC", nil},
+		{"zz", nil},
+		{"12GG", nil},
+	}
+	for _, c := range cases {
+		got, err := parseHex(c.arg)
+		if c.want == nil {
+			if err == nil {
+				t.Errorf("parseHex(%q) = % X, want error", c.arg, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseHex(%q) = %v", c.arg, err)
+			continue
+		}
+		if !bytes.Equal(got, c.want) {
+			t.Errorf("parseHex(%q) = % X, want % X", c.arg, got, c.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	defer os.Unsetenv("SPI_DEVICE")
+	defer os.Unsetenv("SPI_SPEED")
+	os.Unsetenv("SPI_MODE")
+	os.Setenv("SPI_DEVICE", "/dev/spidev1.0")
+	os.Setenv("SPI_SPEED", "500000")
+	fs := flag.NewFlagSet("spitest", flag.ContinueOnError)
+	dev := fs.String("d", "/dev/spidev5.1", "")
+	speed := fs.Int("s", 1000000, "")
+	mode := fs.Int("mode", -1, "")
+	err := fs.Parse([]string{"-s", "2000000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = applyEnv(fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The environment overrides the default, the flag overrides the environment,
+	// and an unset variable leaves the default.
+	if *dev != "/dev/spidev1.0" {
+		t.Errorf("device = %q, want value of SPI_DEVICE", *dev)
+	}
+	if *speed != 2000000 {
+		t.Errorf("speed = %d, want value of -s flag", *speed)
+	}
+	if *mode != -1 {
+		t.Errorf("mode = %d, want default", *mode)
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	defer os.Unsetenv("SPI_SPEED")
+	os.Setenv("SPI_SPEED", "fast")
+	fs := flag.NewFlagSet("spitest", flag.ContinueOnError)
+	fs.String("d", "", "")
+	fs.Int("s", 0, "")
+	fs.Int("mode", -1, "")
+	err := applyEnv(fs)
+	if err == nil {
+		t.Error("applyEnv accepted SPI_SPEED=fast")
+	}
+}
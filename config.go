@@ -0,0 +1,125 @@
+package spi
+
+// Config holds the configuration of an SPI device.
+type Config struct {
+	Mode        Mode
+	BitsPerWord int
+	LSBFirst    bool
+	MaxSpeed    int
+}
+
+// GetConfig returns the current configuration of the SPI device.
+func (dev *Device) GetConfig() (Config, error) {
+	var c Config
+	var err error
+	c.Mode, err = dev.Mode()
+	if err != nil {
+		return c, err
+	}
+	c.BitsPerWord, err = dev.BitsPerWord()
+	if err != nil {
+		return c, err
+	}
+	c.LSBFirst, err = dev.LSBFirst()
+	if err != nil {
+		return c, err
+	}
+	c.MaxSpeed, err = dev.MaxSpeed()
+	return c, err
+}
+
+// Settings holds the configuration of an SPI device
+// together with the speed requested in each transfer.
+type Settings struct {
+	Config
+	Speed int
+}
+
+// Settings returns the current configuration of the SPI device
+// and the per-transfer speed (see Speed).
+// The values are read separately, so a concurrent change
+// to the device may be only partly reflected.
+func (dev *Device) Settings() (Settings, error) {
+	c, err := dev.GetConfig()
+	return Settings{Config: c, Speed: dev.Speed()}, err
+}
+
+// SetConfig applies the given configuration to the SPI device.
+func (dev *Device) SetConfig(c Config) error {
+	err := dev.SetMode(c.Mode)
+	if err != nil {
+		return err
+	}
+	err = dev.SetBitsPerWord(c.BitsPerWord)
+	if err != nil {
+		return err
+	}
+	err = dev.SetLSBFirst(c.LSBFirst)
+	if err != nil {
+		return err
+	}
+	return dev.SetMaxSpeed(c.MaxSpeed)
+}
+
+// ResetDefaults restores mode 0, 8 bits per word, MSB-first bit order,
+// and the speed with which the device was opened.
+func (dev *Device) ResetDefaults() error {
+	err := dev.SetMode(0)
+	if err != nil {
+		return err
+	}
+	err = dev.SetBitsPerWord(8)
+	if err != nil {
+		return err
+	}
+	err = dev.SetLSBFirst(false)
+	if err != nil {
+		return err
+	}
+	dev.mu.Lock()
+	dev.speed = dev.openSpeed
+	dev.mu.Unlock()
+	if dev.openSpeed == 0 {
+		return nil
+	}
+	return dev.SetMaxSpeed(dev.openSpeed)
+}
+
+// WithSpeed calls fn with the per-transfer speed temporarily set
+// to the given value (in Hertz), and restores the previous speed
+// when fn returns. The speed is checked as by SetSpeed,
+// so it may be higher than the speed the device was opened with.
+func (dev *Device) WithSpeed(speed int, fn func() error) error {
+	prev := dev.Speed()
+	err := dev.SetSpeed(speed)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		dev.mu.Lock()
+		dev.speed = prev
+		dev.mu.Unlock()
+	}()
+	return fn()
+}
+
+// WithLSBFirst calls fn with the device temporarily set
+// to LSB-first bit order, and restores the previous bit order
+// when fn returns.
+func (dev *Device) WithLSBFirst(fn func() error) (err error) {
+	prev, err := dev.LSBFirst()
+	if err != nil {
+		return err
+	}
+	err = dev.SetLSBFirst(true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		restoreErr := dev.SetLSBFirst(prev)
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,19 @@
+package spi
+
+// Conn is the interface satisfied by SPI devices.
+// Drivers can use it instead of *Device so that a fake
+// can be substituted when testing without hardware.
+type Conn interface {
+	Transfer(snd, rcv []byte) error
+	Mode() (Mode, error)
+	SetMode(mode Mode) error
+	LSBFirst() (bool, error)
+	SetLSBFirst(lsb bool) error
+	BitsPerWord() (int, error)
+	SetBitsPerWord(n int) error
+	MaxSpeed() (int, error)
+	SetMaxSpeed(n int) error
+	Close() error
+}
+
+var _ Conn = (*Device)(nil)
@@ -0,0 +1,78 @@
+package spi
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TransferContext performs an SPI transfer operation,
+// returning ctx.Err() if the context is done before the transfer completes.
+// A transfer still waiting for the device at that point is not made,
+// but an ioctl already in progress may still complete in the background,
+// so the buffers must not be reused until it does.
+func (dev *Device) TransferContext(ctx context.Context, snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	return dev.transfer(ctx, snd, rcv, len(snd))
+}
+
+// TransferTimeout performs an SPI transfer operation,
+// returning context.DeadlineExceeded if it does not complete within d.
+// As with TransferContext, the ioctl may still complete in the background,
+// so the buffers must not be reused until it does,
+// and later transfers wait for it to finish.
+func (dev *Device) TransferTimeout(snd, rcv []byte, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return dev.TransferContext(ctx, snd, rcv)
+}
+
+// SetTransferDeadline sets a deadline for subsequent transfers
+// made with the methods of Device, including Write and Read:
+// a transfer that is still waiting for the device or in progress
+// at the deadline fails with context.DeadlineExceeded.
+// It does not apply to the transfers made with Tx inside a Transaction.
+// A transfer still waiting for the device at the deadline is not made,
+// but as with TransferContext, an ioctl already in progress
+// may still complete in the background.
+// A zero value for t means transfers will not time out.
+func (dev *Device) SetTransferDeadline(t time.Time) {
+	dev.deadlineMu.Lock()
+	defer dev.deadlineMu.Unlock()
+	dev.deadline = t
+}
+
+func (dev *Device) transferDeadline() time.Time {
+	dev.deadlineMu.Lock()
+	defer dev.deadlineMu.Unlock()
+	return dev.deadline
+}
+
+// runDeadline calls fn in a new goroutine with a context that expires
+// at the deadline, returning context.DeadlineExceeded if fn has not returned by then.
+func runDeadline(deadline time.Time, fn func(context.Context) error) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	return runContext(ctx, fn)
+}
+
+// runContext calls fn(ctx) in a new goroutine,
+// returning ctx.Err() if the context is done before fn returns.
+func runContext(ctx context.Context, fn func(context.Context) error) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
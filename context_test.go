@@ -0,0 +1,81 @@
+package spi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransferDeadlineMethods(t *testing.T) {
+	dev := &Device{path: "test", fd: -1}
+	dev.SetTransferDeadline(time.Now().Add(-time.Second))
+	buf := make([]byte, 4)
+	_, writeErr := dev.Write(buf)
+	_, readErr := dev.Read(buf)
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"Transfer", dev.Transfer(buf, buf)},
+		{"Write", writeErr},
+		{"Read", readErr},
+		{"TransferAt", dev.TransferAt(1000000, buf, buf)},
+		{"TransferDelay", dev.TransferDelay(buf, buf, time.Microsecond)},
+		{"TransferCS", dev.TransferCS(0, buf, buf)},
+		{"TransferMessages", dev.TransferMessages([]Message{{Snd: buf, Rcv: buf}})},
+		{"TransferLarge", dev.TransferLarge(buf, buf)},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, context.DeadlineExceeded) {
+			t.Errorf("%s after deadline: err = %v, want %v", c.name, c.err, context.DeadlineExceeded)
+		}
+	}
+}
+
+func TestTransferCanceledWhileWaiting(t *testing.T) {
+	// A transfer that gets the device only after its context is done
+	// must not be made.
+	dev := &Device{path: "test", fd: -1, speedLimit: 1000000}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	buf := make([]byte, 4)
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"transfer", dev.transferBuffers(ctx, buf, buf, len(buf))},
+		{"transferAt", dev.transferAt(ctx, 1000000, buf, buf)},
+		{"transferDelay", dev.transferDelay(ctx, buf, buf, time.Microsecond)},
+		{"transferCS", dev.transferCS(ctx, 0, buf, buf)},
+		{"transferMessages", dev.transferMessages(ctx, []Message{{Snd: buf, Rcv: buf}})},
+		{"transferLarge", dev.transferLarge(ctx, buf, buf)},
+	}
+	for _, c := range cases {
+		if !errors.Is(c.err, context.Canceled) {
+			t.Errorf("%s after cancellation: err = %v, want %v", c.name, c.err, context.Canceled)
+		}
+	}
+	if n := len(dev.Stats().Errors); n != 0 {
+		t.Errorf("%d failed ioctls recorded, want none", n)
+	}
+}
+
+func TestTransferContextWaiting(t *testing.T) {
+	// A transfer queued behind another one is not made
+	// if its context is done by the time the device is free.
+	dev := &Device{path: "test", fd: -1}
+	dev.mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	buf := make([]byte, 4)
+	err := dev.TransferContext(ctx, buf, buf)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TransferContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	dev.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	if n := len(dev.Stats().Errors); n != 0 {
+		t.Errorf("%d failed ioctls recorded, want none", n)
+	}
+}
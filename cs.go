@@ -0,0 +1,213 @@
+package spi
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+)
+
+// Custom chip selects are configured as active-low GPIOs,
+// so writing true drives one low and writing false drives it high.
+// Writing csActive selects the device: csActive is false only while
+// the CS_HIGH mode flag is set, so by default the chip select is active low.
+
+// HasCustomCS reports whether the device uses a custom chip select.
+func (dev *Device) HasCustomCS() bool {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return len(dev.cs) != 0
+}
+
+// SelectCS selects which custom chip select is used by subsequent transfers,
+// as an index into the pins given with WithCustomCS.
+func (dev *Device) SelectCS(index int) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err := dev.checkCSIndex(index)
+	if err != nil {
+		return err
+	}
+	dev.csIndex = index
+	return nil
+}
+
+// TransferCS performs an SPI transfer operation using the given custom chip select,
+// without changing the one selected for other transfers.
+func (dev *Device) TransferCS(index int, snd, rcv []byte) error {
+	deadline := dev.transferDeadline()
+	if deadline.IsZero() {
+		return dev.transferCS(context.Background(), index, snd, rcv)
+	}
+	return runDeadline(deadline, func(ctx context.Context) error {
+		return dev.transferCS(ctx, index, snd, rcv)
+	})
+}
+
+// transferCS is TransferCS, but ctx takes the place of the transfer deadline.
+func (dev *Device) transferCS(ctx context.Context, index int, snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = dev.checkCSIndex(index)
+	if err != nil {
+		return err
+	}
+	err = checkLength(len(snd))
+	if err != nil {
+		return err
+	}
+	if len(snd) == 0 {
+		return nil
+	}
+	prev := dev.csIndex
+	dev.csIndex = index
+	defer func() { dev.csIndex = prev }()
+	err = dev.checkWordSize(len(snd))
+	if err != nil {
+		return err
+	}
+	tr := dev.newTransfer(snd, rcv, len(snd))
+	return dev.single(snd, rcv, tr)
+}
+
+// AssertCS asserts the selected custom chip select.
+// It is intended for use with SetManualCS, to keep the chip select
+// asserted across several transfers.
+func (dev *Device) AssertCS() error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if len(dev.cs) == 0 {
+		return fmt.Errorf("%s: %w", dev.path, ErrNoCustomCS)
+	}
+	return dev.assertCS()
+}
+
+// DeassertCS deasserts the selected custom chip select.
+func (dev *Device) DeassertCS() error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if len(dev.cs) == 0 {
+		return fmt.Errorf("%s: %w", dev.path, ErrNoCustomCS)
+	}
+	return dev.deassertCS()
+}
+
+// PulseCS asserts the selected custom chip select for the given duration,
+// without any SPI transfer. This is useful for checking the wiring
+// or triggering a logic analyzer.
+func (dev *Device) PulseCS(d time.Duration) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if len(dev.cs) == 0 {
+		return fmt.Errorf("%s: %w", dev.path, ErrNoCustomCS)
+	}
+	err := dev.assertCS()
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	return dev.deassertCS()
+}
+
+// SetManualCS determines whether the custom chip select is left
+// to the caller (using AssertCS and DeassertCS) instead of being
+// asserted automatically around each transfer.
+// It has no effect on the hardware chip select.
+func (dev *Device) SetManualCS(manual bool) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.manualCS = manual
+}
+
+// autoCS reports whether transfers should assert the custom chip select.
+func (dev *Device) autoCS() bool {
+	return len(dev.cs) != 0 && !dev.manualCS
+}
+
+func (dev *Device) checkCSIndex(index int) error {
+	if index < 0 || index >= len(dev.cs) {
+		return fmt.Errorf("%s: no custom chip select %d", dev.path, index)
+	}
+	return nil
+}
+
+func (dev *Device) assertCS() error {
+	return dev.cs[dev.csIndex].Write(dev.csActive)
+}
+
+func (dev *Device) deassertCS() error {
+	return dev.cs[dev.csIndex].Write(!dev.csActive)
+}
+
+// selectCS asserts the custom chip select at the start of a transfer,
+// then waits for the setup delay.
+func (dev *Device) selectCS() {
+	dev.assertCS()
+	if dev.csSetup > 0 {
+		time.Sleep(dev.csSetup)
+	}
+}
+
+// deselectCS waits for the hold delay at the end of a transfer,
+// then deasserts the custom chip select.
+func (dev *Device) deselectCS() {
+	if dev.csHold > 0 {
+		time.Sleep(dev.csHold)
+	}
+	dev.deassertCS()
+}
+
+// setCSPolarity makes the custom chip selects (if any) active high or low,
+// and leaves them deasserted if the polarity changed.
+func (dev *Device) setCSPolarity(activeHigh bool) error {
+	if dev.csActive == !activeHigh {
+		return nil
+	}
+	dev.csActive = !activeHigh
+	for _, cs := range dev.cs {
+		err := cs.Write(!dev.csActive)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// initialCSHigh reports whether custom chip selects should start out
+// active high, according to WithCSActiveLow, the mode in o,
+// or else the current mode of the device.
+func (dev *Device) initialCSHigh(o *options) bool {
+	switch {
+	case o.csActiveLow != nil:
+		return !*o.csActiveLow
+	case o.mode32 != nil:
+		return *o.mode32&CSHigh != 0
+	case o.mode != nil:
+		return *o.mode&CSHigh != 0
+	}
+	mode, err := dev.Mode()
+	return err == nil && mode&CSHigh != 0
+}
+
+// releaseCS unexports the custom chip select GPIOs,
+// so that they can be acquired again by a later Open.
+func (dev *Device) releaseCS() error {
+	var err error
+	for _, pin := range dev.csPins {
+		e := ioutil.WriteFile("/sys/class/gpio/unexport", []byte(strconv.Itoa(pin)), 0644)
+		if e != nil && err == nil {
+			err = fmt.Errorf("GPIO %d for chip select: %w", pin, e)
+		}
+	}
+	dev.cs = nil
+	dev.csPins = nil
+	return err
+}
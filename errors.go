@@ -0,0 +1,33 @@
+package spi
+
+import (
+	"errors"
+)
+
+var (
+	// ErrDeviceInUse is returned when opening a device
+	// that is locked by another process.
+	ErrDeviceInUse = errors.New("device is in use")
+
+	// ErrDeviceNotFound is returned when opening a device that does not exist.
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrNoCustomCS is returned when an operation requires
+	// a custom chip select but the device does not have one.
+	ErrNoCustomCS = errors.New("no custom chip select")
+)
+
+// A ResponseError is returned by a transfer whose received data
+// was rejected by the response validator (see SetResponseValidator).
+type ResponseError struct {
+	Err error
+}
+
+func (e *ResponseError) Error() string {
+	return "invalid response: " + e.Err.Error()
+}
+
+// Unwrap returns the error from the response validator.
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,37 @@
+package spi
+
+import (
+	"fmt"
+)
+
+// FrameReader performs repeated transfers of fixed-size frames,
+// reusing its buffers between calls.
+type FrameReader struct {
+	dev  *Device
+	zero []byte
+	buf  []byte
+}
+
+// FrameReader returns a FrameReader for frames of the given size.
+func (dev *Device) FrameReader(frameSize int) (*FrameReader, error) {
+	if frameSize <= 0 {
+		return nil, fmt.Errorf("invalid frame size (%d)", frameSize)
+	}
+	return &FrameReader{
+		dev:  dev,
+		zero: make([]byte, frameSize),
+		buf:  make([]byte, frameSize),
+	}, nil
+}
+
+// Next performs a transfer of one frame, sending zeros,
+// and returns the received frame.
+// The returned slice is overwritten by the following call to Next,
+// so it must be copied if it is retained.
+func (r *FrameReader) Next() ([]byte, error) {
+	err := r.dev.Transfer(r.zero, r.buf)
+	if err != nil {
+		return nil, err
+	}
+	return r.buf, nil
+}
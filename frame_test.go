@@ -0,0 +1,22 @@
+package spi
+
+import (
+	"testing"
+)
+
+func TestFrameReaderSize(t *testing.T) {
+	dev := &Device{path: "test", fd: -1}
+	for _, n := range []int{0, -1} {
+		_, err := dev.FrameReader(n)
+		if err == nil {
+			t.Errorf("FrameReader(%d) succeeded", n)
+		}
+	}
+	r, err := dev.FrameReader(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.buf) != 4 {
+		t.Errorf("FrameReader(4): buffer length = %d", len(r.buf))
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !spisim
+// +build !spisim
+
+package spi
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Without the spisim build tag, devices are never simulated (see simulate.go).
+const simulated = false
+
+type simDevice struct{}
+
+// sysIoctl issues an ioctl on the SPI device.
+func (dev *Device) sysIoctl(op uint, arg unsafe.Pointer) unix.Errno {
+	return dev.rawIoctl(op, arg)
+}
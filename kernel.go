@@ -1,5 +1,12 @@
 package spi
 
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
 // Definitions from <linux/spi/spidev.h>
 // C naming is used for ease in keeping this file in sync.
 
@@ -15,7 +22,10 @@ type spi_ioc_transfer struct {
 	cs_change     uint8
 	tx_nbits      uint8
 	rx_nbits      uint8
-	pad           uint16
+
+	// Added in Linux 5.0, in place of part of the padding.
+	word_delay_usecs uint8
+	pad              uint8
 }
 
 // Not all of these are used, but are defined for completeness.
@@ -63,6 +73,57 @@ const (
 	spi_IOC_WR_MODE32 = 0x40046B05
 )
 
+// Kernel-internal error code that some drivers return to user space.
+const errENOTSUPP = unix.Errno(524)
+
+// The size of spi_ioc_transfer is encoded in spi_IOC_MESSAGE_incr;
+// this fails to compile if they disagree.
+var _ = [1]struct{}{}[unsafe.Sizeof(spi_ioc_transfer{})-spi_IOC_MESSAGE_incr>>16]
+
+// KernelTransferStructSize returns the size in bytes of the
+// spi_ioc_transfer structure passed to the kernel in each transfer.
+// The size is part of the ioctl number, so the kernel would reject
+// a mismatched structure with ENOTTY rather than misreading it.
+// It has been 32 bytes on all kernels and architectures:
+// word_delay_usecs, added in Linux 5.0, took over part of the padding,
+// and older kernels ignore it, so nothing depends on the running kernel.
+func KernelTransferStructSize() int {
+	return int(unsafe.Sizeof(spi_ioc_transfer{}))
+}
+
+// maxMessages is the largest number of transfers whose total size
+// fits in the 14-bit size field of the ioctl number.
+const maxMessages = (1<<14 - 1) / int(unsafe.Sizeof(spi_ioc_transfer{}))
+
 func spi_IOC_MESSAGE(n uint) uint {
 	return spi_IOC_MESSAGE_base + n*spi_IOC_MESSAGE_incr
 }
+
+func ioctlName(op uint) string {
+	switch op {
+	case spi_IOC_RD_MODE:
+		return "SPI_IOC_RD_MODE"
+	case spi_IOC_WR_MODE:
+		return "SPI_IOC_WR_MODE"
+	case spi_IOC_RD_LSB_FIRST:
+		return "SPI_IOC_RD_LSB_FIRST"
+	case spi_IOC_WR_LSB_FIRST:
+		return "SPI_IOC_WR_LSB_FIRST"
+	case spi_IOC_RD_BITS_PER_WORD:
+		return "SPI_IOC_RD_BITS_PER_WORD"
+	case spi_IOC_WR_BITS_PER_WORD:
+		return "SPI_IOC_WR_BITS_PER_WORD"
+	case spi_IOC_RD_MAX_SPEED_HZ:
+		return "SPI_IOC_RD_MAX_SPEED_HZ"
+	case spi_IOC_WR_MAX_SPEED_HZ:
+		return "SPI_IOC_WR_MAX_SPEED_HZ"
+	case spi_IOC_RD_MODE32:
+		return "SPI_IOC_RD_MODE32"
+	case spi_IOC_WR_MODE32:
+		return "SPI_IOC_WR_MODE32"
+	}
+	if op&^(0x3FFF<<16) == spi_IOC_MESSAGE_base {
+		return fmt.Sprintf("SPI_IOC_MESSAGE(%d)", (op-spi_IOC_MESSAGE_base)/spi_IOC_MESSAGE_incr)
+	}
+	return fmt.Sprintf("ioctl %#x", op)
+}
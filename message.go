@@ -0,0 +1,187 @@
+package spi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Message represents one segment of a multi-message SPI transfer.
+type Message struct {
+	Snd []byte
+	Rcv []byte
+
+	// Speed overrides the device speed for this segment, if non-zero.
+	// It is checked against the maximum speed as in TransferAt.
+	Speed int
+
+	// Delay, if not nil, is inserted after this segment, before the next
+	// one begins, overriding the device's default delay (see SetDefaultDelay).
+	Delay *time.Duration
+
+	// CSChange, if not nil, sets or clears the kernel's cs_change flag
+	// for this segment, overriding the device default (see SetDefaultCSChange).
+	// Without cs_change, chip select remains asserted between segments
+	// and is deasserted after the last one.
+	// Setting it deasserts chip select after this segment,
+	// or, for the last segment, leaves it asserted after the transfer.
+	CSChange *bool
+
+	// TxLanes and RxLanes set the number of data lines (1, 2, or 4)
+	// used to send and receive this segment, for dual and quad I/O.
+	// Zero means single-line.
+	TxLanes int
+	RxLanes int
+
+	// WordDelay is inserted between words of this segment (up to 255µs).
+	// It requires Linux 5.0 or later; older kernels ignore it.
+	WordDelay time.Duration
+}
+
+// TransferMessages performs a sequence of SPI transfers in a single ioctl.
+// At most 511 messages can be transferred at once.
+func (dev *Device) TransferMessages(msgs []Message) error {
+	deadline := dev.transferDeadline()
+	if deadline.IsZero() {
+		return dev.transferMessages(context.Background(), msgs)
+	}
+	return runDeadline(deadline, func(ctx context.Context) error {
+		return dev.transferMessages(ctx, msgs)
+	})
+}
+
+// transferMessages is TransferMessages, but ctx takes the place of the transfer deadline.
+func (dev *Device) transferMessages(ctx context.Context, msgs []Message) error {
+	if len(msgs) == 0 {
+		return fmt.Errorf("no messages to transfer")
+	}
+	if len(msgs) > maxMessages {
+		return fmt.Errorf("too many messages to transfer (%d); the maximum is %d", len(msgs), maxMessages)
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+	tr, useWordDelay, err := dev.buildMessages(msgs)
+	if err != nil {
+		return err
+	}
+	err = dev.message(tr)
+	// The buffers are referenced only through msgs.
+	runtime.KeepAlive(msgs)
+	if useWordDelay && errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("%w (word delay may not be supported by this kernel or controller)", err)
+	}
+	if err != nil {
+		return err
+	}
+	for i, m := range msgs {
+		err := dev.completed(m.Snd, m.Rcv)
+		if err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// buildMessages validates msgs and returns the corresponding transfers,
+// and whether any of them uses a word delay.
+// The caller must hold dev.mu.
+func (dev *Device) buildMessages(msgs []Message) ([]spi_ioc_transfer, bool, error) {
+	tr := make([]spi_ioc_transfer, len(msgs))
+	useWordDelay := false
+	for i, m := range msgs {
+		if len(m.Snd) != len(m.Rcv) {
+			return nil, false, fmt.Errorf("message %d: transfer buffers must be the same length (snd = %d, rcv = %d)", i, len(m.Snd), len(m.Rcv))
+		}
+		err := checkLength(len(m.Snd))
+		if err != nil {
+			return nil, false, fmt.Errorf("message %d: %w", i, err)
+		}
+		delay := dev.delay
+		if m.Delay != nil {
+			delay, err = delayUsecs(*m.Delay)
+			if err != nil {
+				return nil, false, fmt.Errorf("message %d: %w", i, err)
+			}
+		}
+		wordDelay, err := wordDelayUsecs(m.WordDelay)
+		if err != nil {
+			return nil, false, fmt.Errorf("message %d: %w", i, err)
+		}
+		if wordDelay != 0 {
+			useWordDelay = true
+		}
+		tx, err := lanes(m.TxLanes)
+		if err != nil {
+			return nil, false, fmt.Errorf("message %d: TxLanes: %w", i, err)
+		}
+		rx, err := lanes(m.RxLanes)
+		if err != nil {
+			return nil, false, fmt.Errorf("message %d: RxLanes: %w", i, err)
+		}
+		if m.Speed < 0 {
+			return nil, false, fmt.Errorf("message %d: invalid speed (%d Hz)", i, m.Speed)
+		}
+		if m.Speed > 0 {
+			err = dev.checkSpeed(m.Speed)
+			if err != nil {
+				return nil, false, fmt.Errorf("message %d: %w", i, err)
+			}
+		}
+		err = dev.checkWordSize(len(m.Snd))
+		if err != nil {
+			return nil, false, fmt.Errorf("message %d: %w", i, err)
+		}
+		tr[i] = dev.newTransfer(m.Snd, m.Rcv, len(m.Snd))
+		tr[i].tx_nbits = tx
+		tr[i].rx_nbits = rx
+		if m.Speed != 0 {
+			tr[i].speed_hz = uint32(m.Speed)
+		}
+		tr[i].delay_usecs = delay
+		tr[i].word_delay_usecs = wordDelay
+		if m.CSChange != nil {
+			tr[i].cs_change = 0
+			if *m.CSChange {
+				tr[i].cs_change = 1
+			}
+		}
+	}
+	return tr, useWordDelay, nil
+}
+
+// delayUsecs converts d to the microsecond delay used in spi_ioc_transfer.
+func delayUsecs(d time.Duration) (uint16, error) {
+	us := d / time.Microsecond
+	if us < 0 || us > math.MaxUint16 {
+		return 0, fmt.Errorf("delay %v out of range (0 to %dµs)", d, math.MaxUint16)
+	}
+	return uint16(us), nil
+}
+
+// wordDelayUsecs converts d to the microsecond word delay used in spi_ioc_transfer.
+func wordDelayUsecs(d time.Duration) (uint8, error) {
+	us := d / time.Microsecond
+	if us < 0 || us > math.MaxUint8 {
+		return 0, fmt.Errorf("word delay %v out of range (0 to %dµs)", d, math.MaxUint8)
+	}
+	return uint8(us), nil
+}
+
+// lanes validates a lane count for use as tx_nbits or rx_nbits.
+func lanes(n int) (uint8, error) {
+	switch n {
+	case 0, 1, 2, 4:
+		return uint8(n), nil
+	default:
+		return 0, fmt.Errorf("invalid number of lanes (%d); must be 1, 2, or 4", n)
+	}
+}
@@ -0,0 +1,128 @@
+package spi
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestBuildMessagesCSChange(t *testing.T) {
+	dev := &Device{}
+	cmd := []byte{0x2C}
+	data := make([]byte, 16)
+	csChange := true
+	msgs := []Message{
+		{Snd: cmd, Rcv: make([]byte, len(cmd)), CSChange: &csChange},
+		{Snd: data, Rcv: make([]byte, len(data))},
+	}
+	tr, _, err := dev.buildMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 32
+	if len(tr) != 2 || unsafe.Sizeof(tr[0]) != size {
+		t.Fatalf("got %d transfers of %d bytes, want 2 of %d", len(tr), unsafe.Sizeof(tr[0]), size)
+	}
+	// Layout of struct spi_ioc_transfer in <linux/spi/spidev.h>.
+	const (
+		lenOffset      = 16
+		csChangeOffset = 27
+	)
+	if unsafe.Offsetof(tr[0].len) != lenOffset || unsafe.Offsetof(tr[0].cs_change) != csChangeOffset {
+		t.Fatalf("len at offset %d and cs_change at %d, want %d and %d",
+			unsafe.Offsetof(tr[0].len), unsafe.Offsetof(tr[0].cs_change), lenOffset, csChangeOffset)
+	}
+	b := (*[2 * size]byte)(unsafe.Pointer(&tr[0]))
+	if b[csChangeOffset] != 1 {
+		t.Errorf("message 0: cs_change byte = %d, want 1", b[csChangeOffset])
+	}
+	if b[size+csChangeOffset] != 0 {
+		t.Errorf("message 1: cs_change byte = %d, want 0 (deassert after the last message)", b[size+csChangeOffset])
+	}
+	len0 := nativeEndian.Uint32(b[lenOffset:])
+	len1 := nativeEndian.Uint32(b[size+lenOffset:])
+	if len0 != uint32(len(cmd)) || len1 != uint32(len(data)) {
+		t.Errorf("len fields = %d, %d, want %d, %d", len0, len1, len(cmd), len(data))
+	}
+}
+
+func TestBuildMessagesOverride(t *testing.T) {
+	// Messages can clear cs_change and the delay
+	// even when the device defaults set them.
+	dev := &Device{csChange: true, delay: 10}
+	keep := false
+	noDelay := time.Duration(0)
+	buf := make([]byte, 2)
+	msgs := []Message{
+		{Snd: buf, Rcv: buf, CSChange: &keep, Delay: &noDelay},
+		{Snd: buf, Rcv: buf},
+	}
+	tr, _, err := dev.buildMessages(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr[0].cs_change != 0 {
+		t.Errorf("message 0: cs_change = %d, want 0 (overridden)", tr[0].cs_change)
+	}
+	if tr[1].cs_change != 1 {
+		t.Errorf("message 1: cs_change = %d, want 1 (device default)", tr[1].cs_change)
+	}
+	if tr[0].delay_usecs != 0 {
+		t.Errorf("message 0: delay_usecs = %d, want 0 (overridden)", tr[0].delay_usecs)
+	}
+	if tr[1].delay_usecs != 10 {
+		t.Errorf("message 1: delay_usecs = %d, want 10 (device default)", tr[1].delay_usecs)
+	}
+}
+
+func TestBuildMessagesSpeed(t *testing.T) {
+	dev := &Device{speedLimit: 1000000}
+	buf := make([]byte, 2)
+	cases := []struct {
+		speed int
+		valid bool
+	}{
+		{0, true},
+		{500000, true},
+		{1000000, true},
+		{1000001, false},
+		{-1, false},
+	}
+	for _, c := range cases {
+		msgs := []Message{{Snd: buf, Rcv: buf, Speed: c.speed}}
+		tr, _, err := dev.buildMessages(msgs)
+		if (err == nil) != c.valid {
+			t.Errorf("Speed %d: err = %v, want valid = %v", c.speed, err, c.valid)
+			continue
+		}
+		if err == nil && c.speed != 0 && tr[0].speed_hz != uint32(c.speed) {
+			t.Errorf("Speed %d: speed_hz = %d", c.speed, tr[0].speed_hz)
+		}
+	}
+}
+
+func TestTransferMessagesLimit(t *testing.T) {
+	if maxMessages != 511 {
+		t.Errorf("maxMessages = %d, want 511", maxMessages)
+	}
+	dev := &Device{path: "test", fd: -1}
+	buf := make([]byte, 1)
+	msgs := make([]Message, maxMessages+1)
+	for i := range msgs {
+		msgs[i] = Message{Snd: buf, Rcv: buf}
+	}
+	err := dev.TransferMessages(msgs[:maxMessages])
+	if !errors.Is(err, unix.EBADF) {
+		t.Errorf("TransferMessages(%d messages) = %v, want %v from the ioctl", maxMessages, err, unix.EBADF)
+	}
+	err = dev.TransferMessages(msgs)
+	if err == nil || errors.Is(err, unix.EBADF) {
+		t.Errorf("TransferMessages(%d messages) = %v, want rejection before the ioctl", len(msgs), err)
+	}
+	if dev.Stats().Errors[unix.EBADF] != 1 {
+		t.Errorf("stats = %+v, want only the first ioctl recorded", dev.Stats())
+	}
+}
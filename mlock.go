@@ -0,0 +1,28 @@
+package spi
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// LockBuffer locks the pages containing b into memory,
+// so that transfers using it do not incur page faults.
+// This can reduce latency jitter, especially with DMA-capable controllers.
+// The buffer should be long-lived, such as one allocated once
+// and kept in a struct field, rather than a local variable
+// that may be on a goroutine stack, which the Go runtime can move.
+//
+// The amount of memory a process can lock is limited by RLIMIT_MEMLOCK,
+// which is often only 64 KiB or 8 MiB for unprivileged processes;
+// beyond it, LockBuffer fails with ENOMEM (or EPERM if the limit is 0).
+// Processes with CAP_IPC_LOCK are not limited.
+// Locks do not stack: unlocking any buffer unlocks every page it shares
+// with other locked buffers, so buffers locked separately
+// should not share pages.
+func LockBuffer(b []byte) error {
+	return unix.Mlock(b)
+}
+
+// UnlockBuffer unlocks the pages containing b, which were locked with LockBuffer.
+func UnlockBuffer(b []byte) error {
+	return unix.Munlock(b)
+}
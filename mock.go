@@ -0,0 +1,151 @@
+package spi
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// Exchange records the bytes sent and received in a single transfer.
+type Exchange struct {
+	Snd []byte
+	Rcv []byte
+}
+
+// Mock is an in-memory Conn for testing drivers without hardware.
+// Expected transfers are registered with Expect, and are checked
+// and answered in order as the driver performs them.
+type Mock struct {
+	mu       sync.Mutex
+	expected []Exchange
+	log      []Exchange
+	mode     Mode
+	lsb      bool
+	bits     int
+	speed    int
+}
+
+var _ Conn = (*Mock)(nil)
+
+// NewMock returns a Mock with no expected transfers.
+func NewMock() *Mock {
+	return &Mock{bits: 8}
+}
+
+// Expect registers a transfer that must send snd,
+// and to which the mock responds with rcv.
+func (m *Mock) Expect(snd, rcv []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expected = append(m.expected, Exchange{Snd: snd, Rcv: rcv})
+}
+
+// Transfer checks snd against the next expected transfer
+// and fills rcv with its response.
+func (m *Mock) Transfer(snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := len(m.log)
+	if len(m.expected) == 0 {
+		return fmt.Errorf("mock: unexpected transfer %d (send: % X)", n, snd)
+	}
+	exp := m.expected[0]
+	if !bytes.Equal(snd, exp.Snd) {
+		return fmt.Errorf("mock: transfer %d sent % X instead of % X", n, snd, exp.Snd)
+	}
+	if len(exp.Rcv) != len(rcv) {
+		return fmt.Errorf("mock: transfer %d response has length %d instead of %d", n, len(exp.Rcv), len(rcv))
+	}
+	m.expected = m.expected[1:]
+	copy(rcv, exp.Rcv)
+	m.log = append(m.log, Exchange{
+		Snd: append([]byte(nil), snd...),
+		Rcv: append([]byte(nil), rcv...),
+	})
+	return nil
+}
+
+// Log returns the transfers performed so far.
+func (m *Mock) Log() []Exchange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Exchange(nil), m.log...)
+}
+
+// Done returns an error if any expected transfers have not been performed.
+func (m *Mock) Done() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.expected) != 0 {
+		return fmt.Errorf("mock: %d expected transfers not performed (next send: % X)", len(m.expected), m.expected[0].Snd)
+	}
+	return nil
+}
+
+// Mode returns the mode last set with SetMode.
+func (m *Mock) Mode() (Mode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mode, nil
+}
+
+// SetMode records the mode.
+func (m *Mock) SetMode(mode Mode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mode = mode
+	return nil
+}
+
+// LSBFirst returns the bit order last set with SetLSBFirst.
+func (m *Mock) LSBFirst() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lsb, nil
+}
+
+// SetLSBFirst records the bit order.
+func (m *Mock) SetLSBFirst(lsb bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lsb = lsb
+	return nil
+}
+
+// BitsPerWord returns the word size last set with SetBitsPerWord.
+func (m *Mock) BitsPerWord() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.bits, nil
+}
+
+// SetBitsPerWord records the word size.
+func (m *Mock) SetBitsPerWord(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bits = n
+	return nil
+}
+
+// MaxSpeed returns the speed last set with SetMaxSpeed.
+func (m *Mock) MaxSpeed() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.speed, nil
+}
+
+// SetMaxSpeed records the speed.
+func (m *Mock) SetMaxSpeed(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.speed = n
+	return nil
+}
+
+// Close does nothing.
+func (m *Mock) Close() error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package spi
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMockTransfer(t *testing.T) {
+	m := NewMock()
+	m.Expect([]byte{0x9F, 0, 0}, []byte{0, 0xEF, 0x40})
+	rcv := make([]byte, 3)
+	err := m.Transfer([]byte{0x9F, 0, 0}, rcv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rcv, []byte{0, 0xEF, 0x40}) {
+		t.Errorf("received % X, want 00 EF 40", rcv)
+	}
+	err = m.Done()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockMismatch(t *testing.T) {
+	m := NewMock()
+	m.Expect([]byte{0x01}, []byte{0x02})
+	err := m.Transfer([]byte{0x03}, make([]byte, 1))
+	if err == nil {
+		t.Error("Transfer with the wrong data succeeded")
+	}
+	if len(m.Log()) != 0 {
+		t.Errorf("failed transfer was logged")
+	}
+	// The expected transfer is still pending.
+	err = m.Transfer([]byte{0x01}, make([]byte, 1))
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockResponseLength(t *testing.T) {
+	m := NewMock()
+	m.Expect([]byte{0x01, 0x02}, []byte{0x03})
+	err := m.Transfer([]byte{0x01, 0x02}, make([]byte, 2))
+	if err == nil {
+		t.Error("Transfer with a short response succeeded")
+	}
+}
+
+func TestMockUnexpected(t *testing.T) {
+	m := NewMock()
+	err := m.Transfer([]byte{0x01}, make([]byte, 1))
+	if err == nil {
+		t.Error("unexpected Transfer succeeded")
+	}
+}
+
+func TestMockLog(t *testing.T) {
+	m := NewMock()
+	m.Expect([]byte{0x01}, []byte{0x02})
+	snd := []byte{0x01}
+	rcv := make([]byte, 1)
+	err := m.Transfer(snd, rcv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The log must not alias the caller's buffers or the mock's own log.
+	snd[0], rcv[0] = 0xFF, 0xFF
+	log := m.Log()
+	if len(log) != 1 {
+		t.Fatalf("log has %d transfers, want 1", len(log))
+	}
+	if log[0].Snd[0] != 0x01 || log[0].Rcv[0] != 0x02 {
+		t.Errorf("log = % X / % X, want 01 / 02", log[0].Snd, log[0].Rcv)
+	}
+	log[0] = Exchange{}
+	if m.Log()[0].Snd == nil {
+		t.Errorf("modifying the result of Log changed the mock")
+	}
+}
+
+func TestMockDone(t *testing.T) {
+	m := NewMock()
+	m.Expect([]byte{0x01}, []byte{0x02})
+	m.Expect([]byte{0x03}, []byte{0x04})
+	err := m.Transfer([]byte{0x01}, make([]byte, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Done() == nil {
+		t.Error("Done succeeded with an expected transfer not performed")
+	}
+}
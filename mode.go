@@ -0,0 +1,209 @@
+package spi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Mode represents the mode flags of an SPI device.
+type Mode uint32
+
+// SPI mode flags.
+const (
+	CPHA Mode = spi_CPHA
+	CPOL Mode = spi_CPOL
+
+	Mode0 Mode = spi_MODE_0
+	Mode1 Mode = spi_MODE_1
+	Mode2 Mode = spi_MODE_2
+	Mode3 Mode = spi_MODE_3
+
+	CSHigh    Mode = spi_CS_HIGH
+	LSBFirst  Mode = spi_LSB_FIRST
+	ThreeWire Mode = spi_3WIRE
+	Loopback  Mode = spi_LOOP
+	NoCS      Mode = spi_NO_CS
+	Ready     Mode = spi_READY
+	TxDual    Mode = spi_TX_DUAL
+	TxQuad    Mode = spi_TX_QUAD
+	RxDual    Mode = spi_RX_DUAL
+	RxQuad    Mode = spi_RX_QUAD
+)
+
+// ModeFromCPOLCPHA returns the clock mode (Mode0 through Mode3)
+// for the given clock polarity and phase.
+func ModeFromCPOLCPHA(cpol, cpha bool) Mode {
+	var m Mode
+	if cpol {
+		m |= CPOL
+	}
+	if cpha {
+		m |= CPHA
+	}
+	return m
+}
+
+// CPOLCPHA returns the clock polarity and phase of m.
+func (m Mode) CPOLCPHA() (cpol, cpha bool) {
+	return m&CPOL != 0, m&CPHA != 0
+}
+
+var modeFlagNames = []struct {
+	flag Mode
+	name string
+}{
+	{CSHigh, "CS_HIGH"},
+	{LSBFirst, "LSB_FIRST"},
+	{ThreeWire, "3WIRE"},
+	{Loopback, "LOOP"},
+	{NoCS, "NO_CS"},
+	{Ready, "READY"},
+	{TxDual, "TX_DUAL"},
+	{TxQuad, "TX_QUAD"},
+	{RxDual, "RX_DUAL"},
+	{RxQuad, "RX_QUAD"},
+}
+
+// String renders the clock mode and the flags that are set,
+// for example "MODE_3|CS_HIGH".
+func (m Mode) String() string {
+	names := []string{fmt.Sprintf("MODE_%d", m&(CPOL|CPHA))}
+	rest := m &^ (CPOL | CPHA)
+	for _, f := range modeFlagNames {
+		if rest&f.flag != 0 {
+			names = append(names, f.name)
+			rest &^= f.flag
+		}
+	}
+	if rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", uint32(rest)))
+	}
+	return strings.Join(names, "|")
+}
+
+// CPOL returns the clock polarity of the SPI device.
+func (dev *Device) CPOL() (bool, error) {
+	return dev.modeFlag(CPOL)
+}
+
+// SetCPOL sets the clock polarity of the SPI device.
+// When true, the clock is high when idle.
+func (dev *Device) SetCPOL(cpol bool) error {
+	return dev.setModeFlag(CPOL, cpol)
+}
+
+// CPHA returns the clock phase of the SPI device.
+func (dev *Device) CPHA() (bool, error) {
+	return dev.modeFlag(CPHA)
+}
+
+// SetCPHA sets the clock phase of the SPI device.
+// When true, data is sampled on the trailing clock edge.
+func (dev *Device) SetCPHA(cpha bool) error {
+	return dev.setModeFlag(CPHA, cpha)
+}
+
+// ThreeWire returns whether the SPI device uses a single bidirectional data line.
+func (dev *Device) ThreeWire() (bool, error) {
+	return dev.modeFlag(ThreeWire)
+}
+
+// Set3Wire enables or disables 3-wire (shared SI/SO) mode.
+func (dev *Device) Set3Wire(enabled bool) error {
+	return dev.setModeFlag(ThreeWire, enabled)
+}
+
+// Loopback returns whether the SPI controller's internal loopback is enabled.
+func (dev *Device) Loopback() (bool, error) {
+	return dev.modeFlag(Loopback)
+}
+
+// SetLoopback enables or disables internal loopback,
+// in which transmitted data is echoed back as received data.
+// This is useful to check a controller without any external wiring.
+func (dev *Device) SetLoopback(enabled bool) error {
+	return dev.setModeFlag(Loopback, enabled)
+}
+
+// CSHigh returns whether the chip select is active high.
+func (dev *Device) CSHigh() (bool, error) {
+	return dev.modeFlag(CSHigh)
+}
+
+// SetCSHigh sets whether the chip select is active high.
+// Custom chip selects follow the CS_HIGH flag however the mode is set,
+// so SetMode, SetMode32, and SetConfig also change their polarity.
+func (dev *Device) SetCSHigh(enabled bool) error {
+	return dev.setModeFlag(CSHigh, enabled)
+}
+
+// NoCS returns whether the hardware chip select is disabled.
+func (dev *Device) NoCS() (bool, error) {
+	return dev.modeFlag(NoCS)
+}
+
+// SetNoCS disables or enables the controller's hardware chip select.
+// It has no effect on a custom chip select, which is still asserted
+// around each transfer.
+func (dev *Device) SetNoCS(enabled bool) error {
+	return dev.setModeFlag(NoCS, enabled)
+}
+
+// Ready returns whether the SPI_READY handshake is enabled.
+func (dev *Device) Ready() (bool, error) {
+	return dev.modeFlag(Ready)
+}
+
+// SetReady enables or disables the SPI_READY handshake,
+// in which the slave can pause the transfer with a READY signal.
+// This requires a controller and wiring that support the READY line;
+// other controllers will usually reject it with EINVAL.
+func (dev *Device) SetReady(enabled bool) error {
+	err := dev.setModeFlag(Ready, enabled)
+	if enabled && errors.Is(err, unix.EINVAL) {
+		return fmt.Errorf("this controller does not support the SPI_READY handshake: %w", err)
+	}
+	return err
+}
+
+// modeFlag returns whether the given bit is set in the device mode.
+func (dev *Device) modeFlag(flag Mode) (bool, error) {
+	mode, err := dev.Mode()
+	return mode&flag != 0, err
+}
+
+// setModeFlag sets or clears the given bit in the device mode,
+// preserving the other bits.
+func (dev *Device) setModeFlag(flag Mode, enabled bool) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	var m uint8
+	err := dev.syscallU8(spi_IOC_RD_MODE, &m)
+	if err != nil {
+		return err
+	}
+	mode := Mode(m)
+	if enabled {
+		mode |= flag
+	} else {
+		mode &^= flag
+	}
+	return dev.setMode(mode)
+}
+
+// checkMode returns an error for combinations of mode flags
+// that the kernel rejects.
+func checkMode(mode Mode) error {
+	switch {
+	case mode&(TxDual|TxQuad) == TxDual|TxQuad:
+		return fmt.Errorf("invalid mode %v: TX_DUAL and TX_QUAD are mutually exclusive", mode)
+	case mode&(RxDual|RxQuad) == RxDual|RxQuad:
+		return fmt.Errorf("invalid mode %v: RX_DUAL and RX_QUAD are mutually exclusive", mode)
+	case mode&ThreeWire != 0 && mode&(TxDual|TxQuad|RxDual|RxQuad) != 0:
+		return fmt.Errorf("invalid mode %v: 3WIRE cannot be combined with dual or quad I/O", mode)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package spi
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckMode(t *testing.T) {
+	cases := []struct {
+		mode  Mode
+		valid bool
+	}{
+		{Mode0, true},
+		{Mode3 | CSHigh | LSBFirst, true},
+		{TxQuad | RxQuad, true},
+		{ThreeWire, true},
+		{TxDual | TxQuad, false},
+		{RxDual | RxQuad, false},
+		{ThreeWire | TxDual, false},
+		{ThreeWire | TxQuad, false},
+		{ThreeWire | RxDual, false},
+		{ThreeWire | RxQuad, false},
+	}
+	for _, c := range cases {
+		err := checkMode(c.mode)
+		if (err == nil) != c.valid {
+			t.Errorf("checkMode(%v) = %v, want valid = %v", c.mode, err, c.valid)
+		}
+	}
+}
+
+func TestSetModeRange(t *testing.T) {
+	// fd -1 makes any ioctl fail with EBADF, which shows that
+	// the mode passed validation and reached the kernel.
+	dev := &Device{path: "test", fd: -1}
+	cases := []struct {
+		mode  Mode
+		valid bool
+	}{
+		{Mode3, true},
+		{0xFF, true},
+		{TxDual, false},
+		{0x100, false},
+	}
+	for _, c := range cases {
+		err := dev.SetMode(c.mode)
+		reached := errors.Is(err, unix.EBADF)
+		if reached != c.valid {
+			t.Errorf("SetMode(%v) = %v, want valid = %v", c.mode, err, c.valid)
+		}
+	}
+	err := dev.SetMode32(TxDual | TxQuad)
+	if err == nil || errors.Is(err, unix.EBADF) {
+		t.Errorf("SetMode32(TX_DUAL|TX_QUAD) = %v, want rejection before the ioctl", err)
+	}
+}
+
+func TestModeCPOLCPHA(t *testing.T) {
+	cases := []struct {
+		cpol, cpha bool
+		mode       Mode
+	}{
+		{false, false, Mode0},
+		{false, true, Mode1},
+		{true, false, Mode2},
+		{true, true, Mode3},
+	}
+	for _, c := range cases {
+		m := ModeFromCPOLCPHA(c.cpol, c.cpha)
+		if m != c.mode {
+			t.Errorf("ModeFromCPOLCPHA(%v, %v) = %v, want %v", c.cpol, c.cpha, m, c.mode)
+		}
+		cpol, cpha := (m | CSHigh).CPOLCPHA()
+		if cpol != c.cpol || cpha != c.cpha {
+			t.Errorf("%v.CPOLCPHA() = %v, %v, want %v, %v", m|CSHigh, cpol, cpha, c.cpol, c.cpha)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	cases := []struct {
+		mode Mode
+		s    string
+	}{
+		{Mode0, "MODE_0"},
+		{Mode3 | CSHigh, "MODE_3|CS_HIGH"},
+		{Mode1 | LSBFirst | NoCS | TxQuad, "MODE_1|LSB_FIRST|NO_CS|TX_QUAD"},
+		{Mode2 | Loopback | 0x3000, "MODE_2|LOOP|0x3000"},
+	}
+	for _, c := range cases {
+		s := c.mode.String()
+		if s != c.s {
+			t.Errorf("Mode(%#x).String() = %q, want %q", uint32(c.mode), s, c.s)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package spi
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// An Option configures a device opened with OpenWith.
+type Option func(*options)
+
+type options struct {
+	speed       int
+	mode        *Mode
+	mode32      *Mode
+	bits        int
+	lsbFirst    *bool
+	access      int
+	openFlags   int
+	customCS    []int
+	csActiveLow *bool
+	csSetup     time.Duration
+	csHold      time.Duration
+	lock        int
+	openTimeout time.Duration
+	readFlag    byte
+	writeFlag   byte
+	burstFlag   byte
+
+	skipSpeedCheck bool
+	autoPad        bool
+}
+
+// WithSpeed sets the speed of the device, in Hertz.
+// It is applied with SetMaxSpeed when the device is opened,
+// and used as the per-transfer speed, which overrides
+// the device default for that transfer.
+func WithSpeed(speed int) Option {
+	return func(o *options) {
+		o.speed = speed
+	}
+}
+
+// WithMode sets the mode of the device.
+func WithMode(mode Mode) Option {
+	return func(o *options) {
+		o.mode = &mode
+	}
+}
+
+// WithMode32 sets the full 32-bit mode of the device,
+// including the dual and quad I/O flags.
+// It takes precedence over WithMode if both are given.
+func WithMode32(mode Mode) Option {
+	return func(o *options) {
+		o.mode32 = &mode
+	}
+}
+
+// WithBitsPerWord sets the word size of the device.
+func WithBitsPerWord(n int) Option {
+	return func(o *options) {
+		o.bits = n
+	}
+}
+
+// WithLSBFirst sets the bit order of the device.
+func WithLSBFirst(lsb bool) Option {
+	return func(o *options) {
+		o.lsbFirst = &lsb
+	}
+}
+
+// WithCustomCS uses the given GPIO pin number as a custom chip select.
+// It can be given more than once to drive several devices on one bus;
+// the first pin is selected initially (see SelectCS).
+// Negative pin numbers, such as NoCustomCS, are ignored.
+func WithCustomCS(pin int) Option {
+	return func(o *options) {
+		if pin >= 0 {
+			o.customCS = append(o.customCS, pin)
+		}
+	}
+}
+
+// WithCSActiveLow sets the polarity of the chip select:
+// if true, a chip select is driven low to select its device;
+// if false, it is driven high.
+// It is applied with SetCSHigh after the mode, so it affects
+// the hardware chip select as well as custom ones.
+// By default the polarity follows the CS_HIGH flag of the mode.
+func WithCSActiveLow(activeLow bool) Option {
+	return func(o *options) {
+		o.csActiveLow = &activeLow
+	}
+}
+
+// WithCSDelays sets the minimum time between asserting a custom chip select
+// and starting a transfer, and between the end of a transfer and deasserting it.
+// The default is no delay.
+func WithCSDelays(setup, hold time.Duration) Option {
+	return func(o *options) {
+		o.csSetup = setup
+		o.csHold = hold
+	}
+}
+
+// WithExclusive determines whether the device is locked for exclusive access.
+// The default is true.
+// The lock is an advisory flock, so it only excludes other processes
+// that also lock the device; use WithExclusive(false) to open
+// multiple handles to the same device deliberately.
+func WithExclusive(exclusive bool) Option {
+	return func(o *options) {
+		if exclusive {
+			o.lock = unix.LOCK_EX | unix.LOCK_NB
+		} else {
+			o.lock = NoLock
+		}
+	}
+}
+
+// NoLock can be passed to WithLock to open the device without locking it.
+const NoLock = 0
+
+// WithLock sets the flock applied to the device when it is opened:
+// unix.LOCK_EX or unix.LOCK_SH, optionally combined with unix.LOCK_NB,
+// or NoLock. The default is unix.LOCK_EX|unix.LOCK_NB (see WithExclusive).
+// A shared lock allows any number of processes that also use LOCK_SH
+// to open the device, while excluding those that use LOCK_EX.
+// Without LOCK_NB, opening a locked device waits until it is unlocked,
+// and WithOpenTimeout has no effect.
+func WithLock(lockType int) Option {
+	return func(o *options) {
+		o.lock = lockType
+	}
+}
+
+// WithAccessMode sets the access mode used to open the device:
+// unix.O_RDWR (the default), unix.O_RDONLY, or unix.O_WRONLY.
+// The device node may then need only read or write permission,
+// but the driver may reject operations that need the missing access.
+func WithAccessMode(mode int) Option {
+	return func(o *options) {
+		o.access = mode
+	}
+}
+
+// WithOpenFlags adds flags such as unix.O_NONBLOCK or unix.O_CLOEXEC
+// to those used to open the device. The access mode is set separately
+// with WithAccessMode, so flags must not include one.
+// The spidev driver itself ignores O_NONBLOCK: it only affects
+// the open call, and transfers still block until they complete.
+func WithOpenFlags(flags int) Option {
+	return func(o *options) {
+		o.openFlags = flags
+	}
+}
+
+// WithOpenTimeout sets how long to keep trying to lock the device
+// while it is in use by another process.
+// The default is 0, so opening a device in use fails immediately.
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithRegisterFlags sets the bits that ReadRegister and WriteRegister
+// combine with the register address.
+// The default is 0x80 for reads and 0 for writes.
+func WithRegisterFlags(read, write byte) Option {
+	return func(o *options) {
+		o.readFlag = read
+		o.writeFlag = write
+	}
+}
+
+// WithBurstFlag sets the bits that ReadRegisters combines with the register address,
+// in addition to the read flag, for devices that require a separate
+// auto-increment or multiple-byte flag. The default is 0.
+func WithBurstFlag(flag byte) Option {
+	return func(o *options) {
+		o.burstFlag = flag
+	}
+}
+
+// WithSpeedCheck determines whether SetSpeed and TransferAt
+// compare the requested speed with the device's maximum speed (see SetSpeed).
+// The default is true.
+func WithSpeedCheck(check bool) Option {
+	return func(o *options) {
+		o.skipSpeedCheck = !check
+	}
+}
+
+// WithAutoPad determines whether Transfer, Write, and Read pad buffers
+// that are not a whole number of words (with a word size over 8 bits)
+// instead of rejecting them. The data sent is padded with zeros,
+// and only the requested number of bytes is received.
+// Padding copies the data into temporary buffers, which are allocated
+// for each transfer that needs them.
+// The default is false.
+func WithAutoPad(pad bool) Option {
+	return func(o *options) {
+		o.autoPad = pad
+	}
+}
+
+func defaultOptions() options {
+	return options{
+		access:   unix.O_RDWR,
+		lock:     unix.LOCK_EX | unix.LOCK_NB,
+		readFlag: 0x80,
+	}
+}
+
+// OpenWith opens the given SPI device and configures it
+// according to the given options.
+func OpenWith(spiDevice string, opts ...Option) (*Device, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	dev, err := open(spiDevice, &o)
+	if err != nil {
+		return nil, err
+	}
+	err = dev.configure(&o)
+	if err != nil {
+		_ = dev.Close()
+		return nil, err
+	}
+	return dev, nil
+}
+
+func (dev *Device) configure(o *options) error {
+	dev.readFlag = o.readFlag
+	dev.writeFlag = o.writeFlag
+	dev.burstFlag = o.burstFlag
+	dev.skipSpeedCheck = o.skipSpeedCheck
+	dev.autoPad = o.autoPad
+	dev.openSpeed = o.speed
+	dev.csSetup = o.csSetup
+	dev.csHold = o.csHold
+	// Record the device's own maximum speed before WithSpeed changes it.
+	max, err := dev.MaxSpeed()
+	if err == nil {
+		dev.speedLimit = max
+	}
+	return dev.apply(o)
+}
+
+// apply makes the ioctls that set the device configuration given in o.
+func (dev *Device) apply(o *options) error {
+	switch {
+	case o.mode32 != nil:
+		err := dev.SetMode32(*o.mode32)
+		if err != nil {
+			return err
+		}
+	case o.mode != nil:
+		err := dev.SetMode(*o.mode)
+		if err != nil {
+			return err
+		}
+	}
+	if o.csActiveLow != nil {
+		err := dev.SetCSHigh(!*o.csActiveLow)
+		if err != nil {
+			return err
+		}
+	}
+	if o.bits != 0 {
+		err := dev.SetBitsPerWord(o.bits)
+		if err != nil {
+			return err
+		}
+	}
+	if o.lsbFirst != nil {
+		err := dev.SetLSBFirst(*o.lsbFirst)
+		if err != nil {
+			return err
+		}
+	}
+	if o.speed != 0 {
+		err := dev.SetMaxSpeed(o.speed)
+		if err != nil {
+			return fmt.Errorf("setting speed to %d Hz: %w", o.speed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package spi
+
+import (
+	"fmt"
+)
+
+// WriteRead sends w and then receives readLen bytes
+// within a single full-duplex transfer, and returns the received bytes.
+func (dev *Device) WriteRead(w []byte, readLen int) ([]byte, error) {
+	if readLen < 0 {
+		return nil, fmt.Errorf("invalid read length (%d)", readLen)
+	}
+	n := len(w) + readLen
+	buf := make([]byte, n)
+	copy(buf, w)
+	err := dev.TransferInPlace(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[len(w):], nil
+}
+
+// ReadRegister reads an 8-bit register at the given address.
+// The address is combined with the device's register read flag,
+// which is 0x80 by default (see WithRegisterFlags).
+func (dev *Device) ReadRegister(addr byte) (byte, error) {
+	buf := []byte{addr | dev.readFlag, 0}
+	err := dev.TransferInPlace(buf)
+	return buf[1], err
+}
+
+// WriteRegister writes an 8-bit register at the given address.
+// The address is combined with the device's register write flag,
+// which is 0 by default (see WithRegisterFlags).
+func (dev *Device) WriteRegister(addr byte, value byte) error {
+	buf := []byte{addr | dev.writeFlag, value}
+	return dev.TransferInPlace(buf)
+}
+
+// ReadRegisters reads n consecutive 8-bit registers starting at the given address,
+// using a single command byte followed by n dummy bytes.
+// The address is combined with the device's register read flag
+// and burst flag (see WithRegisterFlags and WithBurstFlag).
+func (dev *Device) ReadRegisters(start byte, n int) ([]byte, error) {
+	return dev.WriteRead([]byte{start | dev.readFlag | dev.burstFlag}, n)
+}
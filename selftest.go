@@ -0,0 +1,40 @@
+package spi
+
+import (
+	"fmt"
+)
+
+// SelfTest checks the controller and transfer path by enabling loopback,
+// transferring the bytes 0x00 through 0xFF, and verifying that they
+// are received unchanged. The previous mode is restored afterward.
+func (dev *Device) SelfTest() (err error) {
+	prev, err := dev.Mode()
+	if err != nil {
+		return err
+	}
+	err = dev.SetLoopback(true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		restoreErr := dev.SetMode(prev)
+		if err == nil {
+			err = restoreErr
+		}
+	}()
+	snd := make([]byte, 256)
+	for i := range snd {
+		snd[i] = byte(i)
+	}
+	rcv := make([]byte, len(snd))
+	err = dev.Transfer(snd, rcv)
+	if err != nil {
+		return err
+	}
+	for i := range snd {
+		if rcv[i] != snd[i] {
+			return fmt.Errorf("%s: loopback self-test failed at byte %d: sent %02X, received %02X", dev.path, i, snd[i], rcv[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+//go:build spisim
+// +build spisim
+
+package spi
+
+import (
+	"reflect"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// simulated is set by SetSimulated.
+var simulated bool
+
+// SetSimulated determines whether Open and OpenWith return simulated devices,
+// which do not use spidev at all: each transfer copies the send buffer
+// to the receive buffer (as if in loopback mode), and the settings
+// made with other ioctls are stored and read back.
+// Requested speeds are not checked against the maximum speed,
+// and custom chip selects are ignored.
+// It affects only devices opened afterward, and should not be called
+// concurrently with opening devices.
+//
+// SetSimulated is intended for testing code that uses this package
+// on machines without SPI hardware, and is only available
+// when building with the spisim tag.
+func SetSimulated(enabled bool) {
+	simulated = enabled
+}
+
+// simDevice holds the settings of a simulated device.
+type simDevice struct {
+	mode     uint32
+	bits     uint8
+	lsbFirst uint8
+	maxSpeed uint32
+
+	// ops records the ioctls other than transfers, for testing.
+	ops []uint
+}
+
+// sysIoctl issues an ioctl on the SPI device, or simulates it.
+func (dev *Device) sysIoctl(op uint, arg unsafe.Pointer) unix.Errno {
+	if dev.sim != nil {
+		return dev.sim.ioctl(op, arg)
+	}
+	return dev.rawIoctl(op, arg)
+}
+
+// ioctl performs an ioctl on a simulated device.
+func (s *simDevice) ioctl(op uint, arg unsafe.Pointer) unix.Errno {
+	if op&^(0x3FFF<<16) == spi_IOC_MESSAGE_base {
+		s.message(op, arg)
+		return 0
+	}
+	s.ops = append(s.ops, op)
+	switch op {
+	case spi_IOC_RD_MODE:
+		*(*uint8)(arg) = uint8(s.mode)
+	case spi_IOC_WR_MODE:
+		s.mode = s.mode&^0xFF | uint32(*(*uint8)(arg))
+	case spi_IOC_RD_MODE32:
+		*(*uint32)(arg) = s.mode
+	case spi_IOC_WR_MODE32:
+		s.mode = *(*uint32)(arg)
+	case spi_IOC_RD_LSB_FIRST:
+		*(*uint8)(arg) = s.lsbFirst
+	case spi_IOC_WR_LSB_FIRST:
+		s.lsbFirst = *(*uint8)(arg)
+	case spi_IOC_RD_BITS_PER_WORD:
+		*(*uint8)(arg) = s.bits
+		if s.bits == 0 {
+			*(*uint8)(arg) = 8
+		}
+	case spi_IOC_WR_BITS_PER_WORD:
+		s.bits = *(*uint8)(arg)
+	case spi_IOC_RD_MAX_SPEED_HZ:
+		*(*uint32)(arg) = s.maxSpeed
+	case spi_IOC_WR_MAX_SPEED_HZ:
+		s.maxSpeed = *(*uint32)(arg)
+	}
+	return 0
+}
+
+// message simulates the transfers of an SPI_IOC_MESSAGE ioctl.
+func (s *simDevice) message(op uint, arg unsafe.Pointer) {
+	n := (op - spi_IOC_MESSAGE_base) / spi_IOC_MESSAGE_incr
+	for i := uint(0); i < n; i++ {
+		tr := (*spi_ioc_transfer)(unsafe.Pointer(uintptr(arg) + uintptr(i)*unsafe.Sizeof(spi_ioc_transfer{})))
+		if tr.rx_buf == 0 {
+			continue
+		}
+		rcv := bufferAt(tr.rx_buf, tr.len)
+		if tr.tx_buf == 0 {
+			for j := range rcv {
+				rcv[j] = 0
+			}
+			continue
+		}
+		copy(rcv, bufferAt(tr.tx_buf, tr.len))
+	}
+}
+
+// bufferAt returns the buffer of n bytes at addr,
+// the inverse of bufferAddress.
+func bufferAt(addr uint64, n uint32) []byte {
+	var b []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data = uintptr(addr)
+	h.Len = int(n)
+	h.Cap = int(n)
+	return b
+}
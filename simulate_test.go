@@ -0,0 +1,130 @@
+//go:build spisim
+// +build spisim
+
+package spi
+
+import (
+	"testing"
+)
+
+// These tests use simulated devices: run them with go test -tags spisim.
+
+func openSimulated(t *testing.T, opts ...Option) *Device {
+	t.Helper()
+	SetSimulated(true)
+	defer SetSimulated(false)
+	dev, err := OpenWith("/dev/spidev-simulated", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dev
+}
+
+func TestTransferNoAlloc(t *testing.T) {
+	dev := openSimulated(t, WithSpeed(1000000))
+	defer dev.Close()
+	snd := make([]byte, 64)
+	rcv := make([]byte, 64)
+	for i := range snd {
+		snd[i] = byte(i)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		err := dev.Transfer(snd, rcv)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Transfer: %v allocations per call, want 0", allocs)
+	}
+	if rcv[63] != 63 {
+		t.Errorf("Transfer received % X, want loopback of % X", rcv, snd)
+	}
+}
+
+func TestOpenWithMode32(t *testing.T) {
+	mode := Mode3 | TxQuad | RxQuad
+	dev := openSimulated(t, WithMode(Mode1), WithMode32(mode))
+	defer dev.Close()
+	var wrMode, wrMode32 int
+	for _, op := range dev.sim.ops {
+		switch op {
+		case spi_IOC_WR_MODE:
+			wrMode++
+		case spi_IOC_WR_MODE32:
+			wrMode32++
+		}
+	}
+	if wrMode32 != 1 || wrMode != 0 {
+		t.Errorf("open issued SPI_IOC_WR_MODE32 %d times and SPI_IOC_WR_MODE %d times, want 1 and 0", wrMode32, wrMode)
+	}
+	m, err := dev.Mode32()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != mode {
+		t.Errorf("Mode32() = %v, want %v", m, mode)
+	}
+}
+
+func TestSimulatedSpeed(t *testing.T) {
+	dev := openSimulated(t, WithSpeed(1000000))
+	defer dev.Close()
+	max, err := dev.MaxSpeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max != 1000000 {
+		t.Errorf("MaxSpeed() = %d, want 1000000", max)
+	}
+	err = dev.SetSpeed(20000000)
+	if err != nil {
+		t.Errorf("SetSpeed: %v", err)
+	}
+	buf := make([]byte, 4)
+	err = dev.TransferAt(20000000, buf, buf)
+	if err != nil {
+		t.Errorf("TransferAt: %v", err)
+	}
+	err = dev.WithSpeed(20000000, func() error { return dev.TransferInPlace(buf) })
+	if err != nil {
+		t.Errorf("WithSpeed: %v", err)
+	}
+}
+
+func TestCSPolarityFollowsMode(t *testing.T) {
+	dev := openSimulated(t, WithMode(Mode0|CSHigh))
+	defer dev.Close()
+	if dev.csActive {
+		t.Errorf("WithMode(CS_HIGH): custom chip select is active low")
+	}
+	err := dev.SetMode(Mode0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dev.csActive {
+		t.Errorf("SetMode(MODE_0): custom chip select is active high")
+	}
+	err = dev.SetMode32(Mode3 | CSHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.csActive {
+		t.Errorf("SetMode32(MODE_3|CS_HIGH): custom chip select is active low")
+	}
+}
+
+func TestWithCSActiveLow(t *testing.T) {
+	dev := openSimulated(t, WithMode(Mode1), WithCSActiveLow(false))
+	defer dev.Close()
+	mode, err := dev.Mode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != Mode1|CSHigh {
+		t.Errorf("mode = %v, want %v", mode, Mode1|CSHigh)
+	}
+	if dev.csActive {
+		t.Errorf("WithCSActiveLow(false): custom chip select is active low")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build go1.21
+// +build go1.21
+
+package spi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetLogger sets a logger for transfers on the device.
+// Failed transfers are logged at the error level,
+// with the device path and errno, and successful ones
+// at the debug level, with the number of bytes sent and received.
+// Passing nil disables logging, which is the default.
+// SetLogger requires Go 1.21 or later.
+func (dev *Device) SetLogger(l *slog.Logger) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if l == nil {
+		dev.log = nil
+		return
+	}
+	dev.log = func(n int, sent, received uint64, err error) {
+		if err != nil {
+			var errno unix.Errno
+			errors.As(err, &errno)
+			l.Error("SPI transfer failed", "device", dev.path, "errno", int(errno), "error", err)
+			return
+		}
+		if !l.Enabled(context.Background(), slog.LevelDebug) {
+			return
+		}
+		l.Debug("SPI transfer", "device", dev.path, "transfers", n, "sent", sent, "received", received)
+	}
+}
@@ -1,7 +1,14 @@
 package spi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/ecc1/gpio"
@@ -9,83 +16,683 @@ import (
 )
 
 // Device represents an SPI device.
+// It is safe for concurrent use by multiple goroutines.
 type Device struct {
+	// stats must be first so that its counters are 64-bit aligned.
+	stats stats
+
+	// mu serializes ioctls (including each transfer together with
+	// its custom chip select handling) and guards the fields below.
+	mu sync.Mutex
+
+	path  string
 	fd    int
 	speed int
-	cs    gpio.OutputPin
+	bits  uint8
+
+	// sim holds the state of a simulated device (see SetSimulated),
+	// and is nil otherwise.
+	sim *simDevice
+
+	// openSpeed is the speed given when the device was opened.
+	openSpeed int
+
+	// speedLimit is the highest speed accepted by SetSpeed and TransferAt:
+	// the maximum speed of the device before it was opened,
+	// or a higher one set since then. It is 0 if unknown.
+	speedLimit int
+
+	// opts holds the options the device was opened with, for Reopen.
+	// It is nil for a device returned by OpenFD.
+	opts *options
+
+	// maxTransfer caches the result of MaxTransferSize.
+	maxTransfer int
+
+	// skipSpeedCheck disables comparing requested speeds with MaxSpeed.
+	skipSpeedCheck bool
+
+	// autoPad pads transfers to a whole number of words (see WithAutoPad).
+	autoPad bool
+
+	// Address flags used by ReadRegister, WriteRegister, and ReadRegisters.
+	readFlag  byte
+	writeFlag byte
+	burstFlag byte
+
+	// cs holds the custom chip selects, if any, on GPIOs csPins.
+	// csIndex selects the one used for transfers,
+	// and csActive is the logical value that asserts it.
+	cs       []gpio.OutputPin
+	csPins   []int
+	csIndex  int
+	csActive bool
+
+	// manualCS disables asserting the custom chip select around transfers.
+	manualCS bool
+
+	// Default delay_usecs and cs_change for each transfer.
+	delay    uint16
+	csChange bool
+
+	// trace, if not nil, is called after each successful transfer.
+	trace func(snd, rcv []byte)
+
+	// validator, if not nil, checks the data received in each successful transfer.
+	validator func(rcv []byte) error
+
+	// log, if not nil, is called after each transfer ioctl (see SetLogger)
+	// with the number of transfers and bytes, so that tr does not escape.
+	log func(n int, sent, received uint64, err error)
+
+	// deadline is set by SetTransferDeadline.
+	// It has its own mutex so that it can be read
+	// while a transfer is in progress.
+	deadlineMu sync.Mutex
+	deadline   time.Time
+
+	// Delays between asserting the custom chip select and the transfer,
+	// and between the transfer and deasserting it.
+	csSetup time.Duration
+	csHold  time.Duration
 }
 
+// NoCustomCS can be passed to Open to use only the hardware chip select.
+const NoCustomCS = -1
+
 // Open opens the given SPI device at the specified speed (in Hertz)
-// If customCS in not zero, that pin number is used as a custom chip-select.
+// If customCS is not negative, that pin number is used as a custom chip-select,
+// which is active low unless the device mode has CS_HIGH (see WithCSActiveLow).
+// The speed is applied to the device as its default maximum speed,
+// and is also requested explicitly in each transfer.
+// The device is locked for exclusive access (see WithExclusive).
 func Open(spiDevice string, speed int, customCS int) (*Device, error) {
-	fd, err := unix.Open(spiDevice, unix.O_RDWR, 0)
+	return OpenWith(spiDevice, WithSpeed(speed), WithCustomCS(customCS))
+}
+
+// OpenFD returns a Device for an SPI device that has already been opened
+// as the given file descriptor, and sets it to the specified speed (in Hertz).
+// If speed is 0, the device's current maximum speed is left unchanged
+// and used for all transfers.
+// No lock is taken; that is the caller's responsibility.
+// Closing the Device closes fd, but fd is left open if OpenFD fails.
+func OpenFD(fd int, speed int) (*Device, error) {
+	o := defaultOptions()
+	o.speed = speed
+	name, err := os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+	if err != nil {
+		name = fmt.Sprintf("fd %d", fd)
+	}
+	dev := &Device{path: name, fd: fd, speed: speed, csActive: true}
+	err = dev.configure(&o)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", spiDevice, err)
+		return nil, err
+	}
+	return dev, nil
+}
+
+func open(spiDevice string, o *options) (*Device, error) {
+	if simulated {
+		return &Device{path: spiDevice, fd: -1, speed: o.speed, csActive: true, opts: o, sim: &simDevice{}}, nil
+	}
+	fd, err := openDevice(spiDevice, o)
+	if err != nil {
+		return nil, err
+	}
+	dev := &Device{path: spiDevice, fd: fd, speed: o.speed, opts: o}
+	dev.csActive = !dev.initialCSHigh(o)
+	// Use specified GPIO pins as custom chip-selects, initially deasserted.
+	for _, pin := range o.customCS {
+		cs, err := gpio.Output(pin, true, !dev.csActive)
+		if err != nil {
+			_ = dev.releaseCS()
+			_ = unix.Close(fd)
+			return nil, fmt.Errorf("GPIO %d for chip select: %w", pin, err)
+		}
+		dev.cs = append(dev.cs, cs)
+		dev.csPins = append(dev.csPins, pin)
+	}
+	return dev, nil
+}
+
+// openDevice opens and locks the SPI device according to o,
+// and returns its file descriptor.
+func openDevice(spiDevice string, o *options) (int, error) {
+	switch o.access {
+	case unix.O_RDWR, unix.O_RDONLY, unix.O_WRONLY:
+	default:
+		return -1, fmt.Errorf("%s: invalid access mode %#x", spiDevice, o.access)
+	}
+	switch o.lock &^ unix.LOCK_NB {
+	case NoLock, unix.LOCK_EX, unix.LOCK_SH:
+	default:
+		return -1, fmt.Errorf("%s: invalid lock type %#x", spiDevice, o.lock)
+	}
+	if o.openFlags&unix.O_ACCMODE != 0 {
+		return -1, fmt.Errorf("%s: open flags %#x include an access mode (use WithAccessMode)", spiDevice, o.openFlags)
+	}
+	fd, err := unix.Open(spiDevice, o.access|o.openFlags, 0)
+	if err == unix.ENOENT {
+		return -1, fmt.Errorf("%s: %w", spiDevice, ErrDeviceNotFound)
+	}
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", spiDevice, err)
+	}
+	if o.lock&^unix.LOCK_NB != NoLock {
+		// Ensure exclusive (or shared) access.
+		err = flock(fd, o.lock, o.openTimeout)
 	}
-	// Ensure exclusive access.
-	err = unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB)
 	switch err {
 	case nil:
-		if customCS == 0 {
-			return &Device{fd: fd, speed: speed}, nil
-		}
+		return fd, nil
 	case unix.EWOULDBLOCK:
 		_ = unix.Close(fd)
-		return nil, fmt.Errorf("%s: device is in use", spiDevice)
+		return -1, fmt.Errorf("%s: %w", spiDevice, ErrDeviceInUse)
 	default:
 		_ = unix.Close(fd)
-		return nil, fmt.Errorf("%s: %w", spiDevice, err)
+		return -1, fmt.Errorf("%s: %w", spiDevice, err)
 	}
-	// Use specified GPIO pin as custom chip-select.
-	cs, err := gpio.Output(customCS, true, false)
-	if err != nil {
-		_ = unix.Close(fd)
-		return nil, fmt.Errorf("GPIO %d for chip select: %w", customCS, err)
+}
+
+// flock applies the given lock to fd, retrying with increasing delays
+// for up to timeout while the device is locked by someone else.
+func flock(fd int, how int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	delay := time.Millisecond
+	for {
+		err := unix.Flock(fd, how)
+		if err != unix.EWOULDBLOCK || !time.Now().Before(deadline) {
+			return err
+		}
+		time.Sleep(delay)
+		if delay < 100*time.Millisecond {
+			delay *= 2
+		}
 	}
-	return &Device{fd: fd, speed: speed, cs: cs}, nil
 }
 
-// Close closes the SPI device.
+// Close closes the SPI device and releases its custom chip selects, if any.
 func (dev *Device) Close() error {
-	return unix.Close(dev.fd)
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.sim != nil {
+		return nil
+	}
+	err := unix.Close(dev.fd)
+	if len(dev.cs) == 0 {
+		return err
+	}
+	csErr := dev.releaseCS()
+	switch {
+	case err == nil:
+		return csErr
+	case csErr != nil:
+		return fmt.Errorf("%w (also %v)", err, csErr)
+	default:
+		return err
+	}
+}
+
+// Reopen closes and reopens the SPI device, then reapplies the mode,
+// word size, bit order, and speed given when it was opened.
+// This can be used to recover from errors such as EIO
+// after a transient controller failure.
+// Custom chip selects are kept, along with the other settings made
+// with methods of the Device (such as SetSpeed), but changes made
+// to the device configuration since it was opened are lost.
+// If the device cannot be reopened, it is left closed.
+// Reopen cannot be used on a Device returned by OpenFD.
+func (dev *Device) Reopen() error {
+	if dev.opts == nil {
+		return fmt.Errorf("%s: cannot reopen a device opened by file descriptor", dev.path)
+	}
+	if dev.sim != nil {
+		return nil
+	}
+	dev.mu.Lock()
+	// Close first, so that the new descriptor can be locked.
+	_ = unix.Close(dev.fd)
+	fd, err := openDevice(dev.path, dev.opts)
+	dev.fd = fd
+	dev.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return dev.apply(dev.opts)
+}
+
+// Sync waits until any transfers in progress on other goroutines
+// have completed. Because spidev transfers are synchronous,
+// all transfers made before Sync is called have completed when it returns.
+func (dev *Device) Sync() error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return nil
+}
+
+// Name returns the path of the SPI device.
+func (dev *Device) Name() string {
+	return dev.path
+}
+
+// String describes the device and its current configuration.
+// Settings that cannot be read are shown as "?".
+func (dev *Device) String() string {
+	mode, bits, speed, lsb := "?", "?", "?", "?"
+	if m, err := dev.Mode(); err == nil {
+		mode = fmt.Sprint(m)
+	}
+	if s, err := dev.MaxSpeed(); err == nil {
+		speed = fmt.Sprint(s)
+	}
+	if b, err := dev.BitsPerWord(); err == nil {
+		bits = fmt.Sprint(b)
+	}
+	if l, err := dev.LSBFirst(); err == nil {
+		lsb = fmt.Sprint(l)
+	}
+	return fmt.Sprintf("SPI(%s mode=%s speed=%sHz bits=%s lsb=%s)", dev.path, mode, speed, bits, lsb)
 }
 
 // Transfer performs an SPI transfer operation (send and receive).
+// A zero-length transfer does nothing.
+// The kernel reads from snd and writes to rcv directly, and no copies are made,
+// so the same buffers can be reused across calls.
+// Nothing is allocated unless a transfer deadline or logger is set,
+// the buffers are padded (see WithAutoPad), or the transfer fails.
+// The buffers are kept alive until the ioctl returns,
+// and it is safe to use them again as soon as Transfer returns,
+// unless it fails with context.DeadlineExceeded (see SetTransferDeadline).
+//
+// An error from the kernel wraps the underlying unix.Errno,
+// which can be retrieved with errors.As. Common values are
+// EMSGSIZE, when the transfer is larger than the spidev buffer
+// (see TransferLarge), and EINVAL, when the controller does not
+// support the requested speed, word size, or mode.
+// EINTR is retried internally and is only returned
+// if the ioctl is interrupted repeatedly.
 func (dev *Device) Transfer(snd, rcv []byte) error {
 	if len(snd) != len(rcv) {
 		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
 	}
-	if dev.cs != nil {
-		dev.cs.Write(true)
-		defer dev.cs.Write(false)
+	return dev.transfer(context.Background(), snd, rcv, len(snd))
+}
+
+// TransferInPlace performs an SPI transfer operation using buf
+// as both the send and receive buffer.
+// The received bytes overwrite the sent bytes.
+func (dev *Device) TransferInPlace(buf []byte) error {
+	return dev.Transfer(buf, buf)
+}
+
+// Query performs an SPI transfer operation sending snd,
+// and returns the received bytes in a new slice of the same length.
+func (dev *Device) Query(snd []byte) ([]byte, error) {
+	rcv := make([]byte, len(snd))
+	err := dev.Transfer(snd, rcv)
+	if err != nil {
+		return nil, err
+	}
+	return rcv, nil
+}
+
+// TransferZeroed is like Query, but returns the receive buffer
+// even if the transfer fails. The buffer is zeroed beforehand,
+// so bytes that were not received are always zero rather than stale.
+// (A successful full-duplex or receive-only transfer writes every byte
+// of the receive buffer; a failed one may write none or only some of them,
+// and a transmit-only Write does not use a receive buffer at all.)
+func (dev *Device) TransferZeroed(snd []byte) ([]byte, error) {
+	rcv := make([]byte, len(snd))
+	err := dev.Transfer(snd, rcv)
+	return rcv, err
+}
+
+// TransferMultiple performs count consecutive SPI transfers of snd
+// and returns the bytes received in each one.
+// Each transfer asserts and deasserts the chip select separately.
+func (dev *Device) TransferMultiple(snd []byte, count int) ([][]byte, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("invalid transfer count (%d)", count)
+	}
+	rcv := make([][]byte, count)
+	for i := range rcv {
+		rcv[i] = make([]byte, len(snd))
+		err := dev.Transfer(snd, rcv[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rcv, nil
+}
+
+// TransferAt performs an SPI transfer operation at the given speed (in Hertz)
+// without changing the speed used by other transfers.
+// The speed is checked as by SetSpeed.
+func (dev *Device) TransferAt(speed int, snd, rcv []byte) error {
+	deadline := dev.transferDeadline()
+	if deadline.IsZero() {
+		return dev.transferAt(context.Background(), speed, snd, rcv)
+	}
+	return runDeadline(deadline, func(ctx context.Context) error {
+		return dev.transferAt(ctx, speed, snd, rcv)
+	})
+}
+
+// transferAt is TransferAt, but ctx takes the place of the transfer deadline.
+func (dev *Device) transferAt(ctx context.Context, speed int, snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	if speed <= 0 {
+		return fmt.Errorf("invalid speed (%d Hz)", speed)
+	}
+	err := dev.checkMaxSpeed(speed)
+	if err != nil {
+		return err
+	}
+	err = checkLength(len(snd))
+	if err != nil {
+		return err
+	}
+	if len(snd) == 0 {
+		return nil
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = dev.checkWordSize(len(snd))
+	if err != nil {
+		return err
+	}
+	tr := dev.newTransfer(snd, rcv, len(snd))
+	tr.speed_hz = uint32(speed)
+	return dev.single(snd, rcv, tr)
+}
+
+// TransferDelay performs an SPI transfer operation followed by the given delay,
+// which takes place after the last word and before the chip select is deasserted.
+// Delays longer than 65535µs are rejected.
+func (dev *Device) TransferDelay(snd, rcv []byte, delay time.Duration) error {
+	deadline := dev.transferDeadline()
+	if deadline.IsZero() {
+		return dev.transferDelay(context.Background(), snd, rcv, delay)
+	}
+	return runDeadline(deadline, func(ctx context.Context) error {
+		return dev.transferDelay(ctx, snd, rcv, delay)
+	})
+}
+
+// transferDelay is TransferDelay, but ctx takes the place of the transfer deadline.
+func (dev *Device) transferDelay(ctx context.Context, snd, rcv []byte, delay time.Duration) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	us, err := delayUsecs(delay)
+	if err != nil {
+		return err
+	}
+	err = checkLength(len(snd))
+	if err != nil {
+		return err
+	}
+	if len(snd) == 0 {
+		return nil
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = dev.checkWordSize(len(snd))
+	if err != nil {
+		return err
+	}
+	tr := dev.newTransfer(snd, rcv, len(snd))
+	tr.delay_usecs = us
+	return dev.single(snd, rcv, tr)
+}
+
+// Write performs a transmit-only SPI transfer.
+// It implements the io.Writer interface.
+func (dev *Device) Write(buf []byte) (int, error) {
+	err := dev.transfer(context.Background(), buf, nil, len(buf))
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Read performs a receive-only SPI transfer, filling buf with the received bytes.
+// Nothing is transmitted, so a device in loopback mode will receive zeros.
+// It implements the io.Reader interface; a successful Read always fills buf,
+// and io.EOF is never returned.
+func (dev *Device) Read(buf []byte) (int, error) {
+	err := dev.transfer(context.Background(), nil, buf, len(buf))
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// transfer performs a single SPI transfer of n bytes,
+// subject to ctx and the transfer deadline.
+// Either snd or rcv may be nil for a half-duplex transfer.
+func (dev *Device) transfer(ctx context.Context, snd, rcv []byte, n int) error {
+	deadline := dev.transferDeadline()
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	if ctx.Done() == nil {
+		return dev.transferBuffers(ctx, snd, rcv, n)
+	}
+	return runContext(ctx, func(ctx context.Context) error {
+		return dev.transferBuffers(ctx, snd, rcv, n)
+	})
+}
+
+// transferBuffers is transfer without the goroutine:
+// it returns ctx.Err() without transferring anything
+// if ctx is done by the time the device is free.
+func (dev *Device) transferBuffers(ctx context.Context, snd, rcv []byte, n int) error {
+	if n == 0 {
+		return nil
+	}
+	err := checkLength(n)
+	if err != nil {
+		return err
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+	if dev.autoPad && n%dev.wordBytes() != 0 {
+		return dev.transferPadded(snd, rcv, n)
 	}
-	sndAddr := uint64(uintptr(unsafe.Pointer(&snd[0])))
-	rcvAddr := uint64(uintptr(unsafe.Pointer(&rcv[0])))
+	err = dev.checkWordSize(n)
+	if err != nil {
+		return err
+	}
+	tr := dev.newTransfer(snd, rcv, n)
+	return dev.single(snd, rcv, tr)
+}
+
+// checkLength returns an error if n bytes do not fit in a single spi_ioc_transfer.
+func checkLength(n int) error {
+	if uint64(n) > math.MaxUint32 {
+		return fmt.Errorf("transfer length %d exceeds maximum (%d)", n, uint32(math.MaxUint32))
+	}
+	return nil
+}
+
+// checkWordSize returns an error if n bytes is not a whole number of words.
+// The caller must hold dev.mu.
+func (dev *Device) checkWordSize(n int) error {
+	size := dev.wordBytes()
+	if n%size != 0 {
+		return fmt.Errorf("transfer length %d is not a multiple of the word size (%d bits = %d bytes)", n, dev.bitsPerWord(), size)
+	}
+	return nil
+}
+
+// newTransfer returns an spi_ioc_transfer for n bytes
+// using the device's speed, word size, and default delay and cs_change.
+// If the speed is 0, so is speed_hz, and the kernel uses the device's max_speed_hz.
+// The caller must hold dev.mu.
+func (dev *Device) newTransfer(snd, rcv []byte, n int) spi_ioc_transfer {
 	tr := spi_ioc_transfer{
-		tx_buf:        sndAddr,
-		rx_buf:        rcvAddr,
-		len:           uint32(len(snd)),
+		tx_buf:        bufferAddress(snd),
+		rx_buf:        bufferAddress(rcv),
+		len:           uint32(n),
 		speed_hz:      uint32(dev.speed),
-		delay_usecs:   0,
-		bits_per_word: 8,
+		delay_usecs:   dev.delay,
+		bits_per_word: dev.bitsPerWord(),
+	}
+	if dev.csChange {
+		tr.cs_change = 1
+	}
+	return tr
+}
+
+// single submits a single transfer of snd and rcv, then calls
+// the trace function and response validator.
+// The caller must hold dev.mu.
+func (dev *Device) single(snd, rcv []byte, tr spi_ioc_transfer) error {
+	err := dev.message([]spi_ioc_transfer{tr})
+	runtime.KeepAlive(snd)
+	runtime.KeepAlive(rcv)
+	if err != nil {
+		return err
 	}
-	return dev.syscall(spi_IOC_MESSAGE(1), unsafe.Pointer(&tr))
+	return dev.completed(snd, rcv)
 }
 
-// Mode returns the mode of the SPI device.
-func (dev *Device) Mode() (uint8, error) {
+// completed calls the trace function and response validator, if any,
+// after a successful transfer of snd and rcv.
+// The caller must hold dev.mu.
+func (dev *Device) completed(snd, rcv []byte) error {
+	if dev.trace != nil {
+		dev.trace(snd, rcv)
+	}
+	if dev.validator == nil || rcv == nil {
+		return nil
+	}
+	err := dev.validator(rcv)
+	if err != nil {
+		return &ResponseError{Err: err}
+	}
+	return nil
+}
+
+// message submits the consecutive transfers in tr in a single ioctl,
+// asserting the custom chip select (if any) around them
+// unless it is managed manually.
+// The caller must hold dev.mu.
+func (dev *Device) message(tr []spi_ioc_transfer) error {
+	if dev.autoCS() {
+		dev.selectCS()
+		defer dev.deselectCS()
+	}
+	return dev.submit(tr)
+}
+
+// submit issues the ioctl for the transfers in tr and records their statistics.
+// The caller must hold dev.mu.
+func (dev *Device) submit(tr []spi_ioc_transfer) error {
+	err := dev.syscall(spi_IOC_MESSAGE(uint(len(tr))), unsafe.Pointer(&tr[0]))
+	dev.stats.record(tr, err)
+	if dev.log != nil {
+		sent, received := byteCounts(tr)
+		dev.log(len(tr), sent, received, err)
+	}
+	return err
+}
+
+// bufferAddress returns the address of buf as used in spi_ioc_transfer,
+// or 0 if buf is empty.
+// The garbage collector does not treat the result as a reference,
+// so the caller must keep buf alive (with runtime.KeepAlive)
+// until the ioctl using it has returned.
+// Go does not move heap objects, and a goroutine's stack cannot move
+// while it is blocked in a system call, so the address remains valid.
+func bufferAddress(buf []byte) uint64 {
+	if len(buf) == 0 {
+		return 0
+	}
+	return uint64(uintptr(unsafe.Pointer(&buf[0])))
+}
+
+// Mode returns the mode of the SPI device (limited to 8 bits).
+func (dev *Device) Mode() (Mode, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	var mode uint8
 	err := dev.syscallU8(spi_IOC_RD_MODE, &mode)
-	return mode, err
+	return Mode(mode), err
 }
 
 // SetMode sets the mode of the SPI device.
-func (dev *Device) SetMode(mode uint8) error {
-	return dev.syscallU8(spi_IOC_WR_MODE, &mode)
+// Only flags in the low 8 bits can be set this way;
+// use SetMode32 for the others.
+func (dev *Device) SetMode(mode Mode) error {
+	if mode > 0xFF {
+		return fmt.Errorf("mode %v does not fit in 8 bits; use SetMode32", mode)
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.setMode(mode)
+}
+
+// setMode sets the mode of the SPI device and the polarity
+// of any custom chip selects. The caller must hold dev.mu.
+func (dev *Device) setMode(mode Mode) error {
+	m := uint8(mode)
+	err := dev.syscallU8(spi_IOC_WR_MODE, &m)
+	if err != nil {
+		return err
+	}
+	return dev.setCSPolarity(mode&CSHigh != 0)
+}
+
+// Mode32 returns the full 32-bit mode of the SPI device,
+// including the dual and quad I/O flags.
+func (dev *Device) Mode32() (Mode, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	var mode uint32
+	err := dev.syscallU32(spi_IOC_RD_MODE32, &mode)
+	return Mode(mode), err
+}
+
+// SetMode32 sets the full 32-bit mode of the SPI device.
+// Contradictory combinations of flags are rejected.
+func (dev *Device) SetMode32(mode Mode) error {
+	err := checkMode(mode)
+	if err != nil {
+		return err
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	m := uint32(mode)
+	err = dev.syscallU32(spi_IOC_WR_MODE32, &m)
+	if err != nil {
+		return err
+	}
+	return dev.setCSPolarity(mode&CSHigh != 0)
 }
 
 // LSBFirst returns bit order of the SPI device.
 func (dev *Device) LSBFirst() (bool, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	var b uint8
 	err := dev.syscallU8(spi_IOC_RD_LSB_FIRST, &b)
 	if b != 0 {
@@ -95,38 +702,223 @@ func (dev *Device) LSBFirst() (bool, error) {
 }
 
 // SetLSBFirst sets the bit order of the SPI device.
+// If the controller cannot transfer LSB first, the error says so
+// (see also SupportsLSBFirst).
 func (dev *Device) SetLSBFirst(lsb bool) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	var b uint8
 	if lsb {
 		b = 1
 	}
-	return dev.syscallU8(spi_IOC_WR_LSB_FIRST, &b)
+	err := dev.syscallU8(spi_IOC_WR_LSB_FIRST, &b)
+	if lsb && (errors.Is(err, unix.EINVAL) || errors.Is(err, errENOTSUPP)) {
+		return fmt.Errorf("this controller does not support LSB-first bit order: %w", err)
+	}
+	return err
 }
 
 // BitsPerWord returns the word size of the SPI device.
 func (dev *Device) BitsPerWord() (int, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	var bits uint8
 	err := dev.syscallU8(spi_IOC_RD_BITS_PER_WORD, &bits)
 	return int(bits), err
 }
 
-// SetBitsPerWord sets the word size of the SPI device.
+// SetBitsPerWord sets the word size of the SPI device,
+// which must be between 1 and 32.
 func (dev *Device) SetBitsPerWord(n int) error {
+	if n < 1 || n > 32 {
+		return fmt.Errorf("invalid bits per word (%d); must be between 1 and 32", n)
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	bits := uint8(n)
-	return dev.syscallU8(spi_IOC_WR_BITS_PER_WORD, &bits)
+	err := dev.syscallU8(spi_IOC_WR_BITS_PER_WORD, &bits)
+	if err == nil {
+		dev.bits = bits
+	}
+	return err
+}
+
+// bitsPerWord returns the word size to use in transfers:
+// the value last set by SetBitsPerWord, or 8 if it has not been set.
+// The caller must hold dev.mu.
+func (dev *Device) bitsPerWord() uint8 {
+	if dev.bits == 0 {
+		return 8
+	}
+	return dev.bits
+}
+
+// SetDefaultDelay sets the delay after each transfer, before the chip select
+// is deasserted (up to 65535µs). It applies to all transfers
+// unless they specify a delay of their own.
+func (dev *Device) SetDefaultDelay(delay time.Duration) error {
+	us, err := delayUsecs(delay)
+	if err != nil {
+		return err
+	}
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.delay = us
+	return nil
+}
+
+// SetDefaultCSChange sets the kernel's cs_change flag for all transfers.
+// See Message.CSChange for its meaning.
+func (dev *Device) SetDefaultCSChange(csChange bool) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.csChange = csChange
+}
+
+// SetTraceFunc sets a function to be called after each successful transfer
+// with its send and receive buffers, either of which is nil for
+// a half-duplex transfer. Passing nil removes the trace function.
+// The function is called while the device is locked,
+// so it must not use the device itself.
+func (dev *Device) SetTraceFunc(trace func(snd, rcv []byte)) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.trace = trace
+}
+
+// SetResponseValidator sets a function to check the data received
+// in each successful transfer, such as by verifying a checksum.
+// If it returns an error, the transfer returns a *ResponseError wrapping it.
+// Transmit-only transfers are not checked. Passing nil removes the validator.
+// Like the trace function, it is called while the device is locked,
+// so it must not use the device itself.
+func (dev *Device) SetResponseValidator(validate func(rcv []byte) error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	dev.validator = validate
+}
+
+// Speed returns the speed requested in each transfer, in Hertz.
+// This is used by Transfer and related methods,
+// and may differ from the device default set by SetMaxSpeed.
+// A speed of 0 means that transfers use the device default.
+func (dev *Device) Speed() int {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.speed
+}
+
+// SetSpeed sets the speed requested in each transfer, in Hertz.
+// Unlike SetMaxSpeed, it does not reconfigure the device.
+// The actual clock rate may be lower (see SetMaxSpeed).
+// A speed of 0 makes transfers use the device default,
+// rather than overriding it; it does not mean a 0 Hz clock.
+//
+// The speed must not exceed the device's own maximum speed,
+// as it was before the device was opened, or a higher one
+// set later with SetMaxSpeed (unless that check has been disabled
+// with WithSpeedCheck). It may exceed the speed given when opening
+// the device: the kernel uses that only as the default for transfers,
+// so a device opened at 1 MHz can still transfer at 20 MHz.
+func (dev *Device) SetSpeed(speed int) error {
+	if speed < 0 {
+		return fmt.Errorf("invalid speed (%d Hz)", speed)
+	}
+	if speed > 0 {
+		err := dev.checkMaxSpeed(speed)
+		if err != nil {
+			return err
+		}
+	}
+	dev.mu.Lock()
+	dev.speed = speed
+	dev.mu.Unlock()
+	return nil
 }
 
 // MaxSpeed returns the maximum speed of the SPI device, in Hertz.
 func (dev *Device) MaxSpeed() (int, error) {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	var speed uint32
 	err := dev.syscallU32(spi_IOC_RD_MAX_SPEED_HZ, &speed)
 	return int(speed), err
 }
 
 // SetMaxSpeed sets the maximum speed of the SPI device, in Hertz.
+//
+// Like the speed requested in each transfer, this is an upper limit:
+// the controller clocks at the fastest rate it can generate that does
+// not exceed it, which may be considerably slower (for example,
+// when it can only divide its input clock by a power of 2).
+// spidev does not report the rate actually used,
+// and MaxSpeed returns the value that was set.
 func (dev *Device) SetMaxSpeed(n int) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
 	speed := uint32(n)
-	return dev.syscallU32(spi_IOC_WR_MAX_SPEED_HZ, &speed)
+	err := dev.syscallU32(spi_IOC_WR_MAX_SPEED_HZ, &speed)
+	if err == nil && n > dev.speedLimit {
+		dev.speedLimit = n
+	}
+	return err
+}
+
+// ValidSpeed reports whether speed is positive
+// and does not exceed the maximum speed allowed by SetSpeed.
+func (dev *Device) ValidSpeed(speed int) bool {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	max, err := dev.allowedSpeed()
+	return err == nil && 0 < speed && speed <= max
+}
+
+// checkMaxSpeed returns an error if speed exceeds the maximum speed
+// allowed by SetSpeed, unless the check has been disabled with WithSpeedCheck
+// or the device is simulated.
+func (dev *Device) checkMaxSpeed(speed int) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.checkSpeed(speed)
+}
+
+// checkSpeed is like checkMaxSpeed, but the caller must hold dev.mu.
+func (dev *Device) checkSpeed(speed int) error {
+	if dev.skipSpeedCheck || dev.sim != nil {
+		return nil
+	}
+	max, err := dev.allowedSpeed()
+	if err != nil {
+		return err
+	}
+	if speed > max {
+		return fmt.Errorf("speed %d Hz exceeds maximum (%d Hz)", speed, max)
+	}
+	return nil
+}
+
+// allowedSpeed returns the maximum speed allowed by SetSpeed:
+// dev.speedLimit if it is known, otherwise the current maximum speed.
+// The caller must hold dev.mu.
+func (dev *Device) allowedSpeed() (int, error) {
+	if dev.speedLimit != 0 {
+		return dev.speedLimit, nil
+	}
+	var speed uint32
+	err := dev.syscallU32(spi_IOC_RD_MAX_SPEED_HZ, &speed)
+	return int(speed), err
+}
+
+// Ioctl issues the given ioctl on the SPI device,
+// for operations that are not otherwise supported by this package.
+// The caller is responsible for passing an op and arg
+// that match the kernel's definition: the kernel may read or write
+// through arg, so a mismatch can corrupt memory or crash the program.
+// The custom chip select, if any, is not asserted.
+func (dev *Device) Ioctl(op uint, arg unsafe.Pointer) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	return dev.syscall(op, arg)
 }
 
 func (dev *Device) syscallU8(op uint, arg *uint8) error {
@@ -137,10 +929,26 @@ func (dev *Device) syscallU32(op uint, arg *uint32) error {
 	return dev.syscall(op, unsafe.Pointer(arg))
 }
 
+// maxEINTR is the number of times an ioctl is retried
+// when it is interrupted by a signal.
+const maxEINTR = 10
+
 func (dev *Device) syscall(op uint, arg unsafe.Pointer) error {
-	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(dev.fd), uintptr(op), uintptr(arg))
-	if errno != 0 {
-		return error(errno)
+	for i := 0; ; i++ {
+		errno := dev.sysIoctl(op, arg)
+		switch {
+		case errno == 0:
+			return nil
+		case errno == unix.EINTR && i < maxEINTR:
+			continue
+		default:
+			return fmt.Errorf("spi: %s on %s: %w", ioctlName(op), dev.path, errno)
+		}
 	}
-	return nil
+}
+
+// rawIoctl issues an ioctl on the underlying file descriptor.
+func (dev *Device) rawIoctl(op uint, arg unsafe.Pointer) unix.Errno {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(dev.fd), uintptr(op), uintptr(arg))
+	return errno
 }
@@ -0,0 +1,118 @@
+package spi
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNewTransferBitsPerWord(t *testing.T) {
+	dev := &Device{}
+	buf := make([]byte, 4)
+	tr := dev.newTransfer(buf, buf, len(buf))
+	if tr.bits_per_word != 8 {
+		t.Errorf("default bits_per_word = %d, want 8", tr.bits_per_word)
+	}
+	// SetBitsPerWord caches the word size here after a successful ioctl.
+	dev.bits = 16
+	tr = dev.newTransfer(buf, buf, len(buf))
+	if tr.bits_per_word != 16 {
+		t.Errorf("bits_per_word = %d, want 16", tr.bits_per_word)
+	}
+}
+
+func TestTransferEmpty(t *testing.T) {
+	// No ioctl is made for an empty transfer, so no device is needed.
+	dev := &Device{path: "test", fd: -1}
+	cases := []struct {
+		name     string
+		snd, rcv []byte
+	}{
+		{"nil", nil, nil},
+		{"empty", []byte{}, []byte{}},
+	}
+	for _, c := range cases {
+		err := dev.Transfer(c.snd, c.rcv)
+		if err != nil {
+			t.Errorf("Transfer(%s) = %v, want nil", c.name, err)
+		}
+	}
+}
+
+func TestSetBitsPerWordRange(t *testing.T) {
+	// fd -1 makes any ioctl fail with EBADF, which shows that
+	// a valid word size passed validation and reached the kernel.
+	dev := &Device{path: "test", fd: -1}
+	cases := []struct {
+		bits  int
+		valid bool
+	}{
+		{0, false},
+		{1, true},
+		{32, true},
+		{33, false},
+		{300, false},
+	}
+	for _, c := range cases {
+		err := dev.SetBitsPerWord(c.bits)
+		reached := errors.Is(err, unix.EBADF)
+		if reached != c.valid {
+			t.Errorf("SetBitsPerWord(%d) = %v, want valid = %v", c.bits, err, c.valid)
+		}
+	}
+	if dev.bits != 0 {
+		t.Errorf("failed SetBitsPerWord cached bits = %d", dev.bits)
+	}
+}
+
+func TestCheckWordSize(t *testing.T) {
+	cases := []struct {
+		bits  uint8
+		n     int
+		valid bool
+	}{
+		{8, 3, true},
+		{12, 4, true},
+		{16, 1, false},
+		{16, 3, false},
+		{16, 4, true},
+		{24, 6, false},
+		{32, 8, true},
+	}
+	for _, c := range cases {
+		dev := &Device{bits: c.bits}
+		err := dev.checkWordSize(c.n)
+		if (err == nil) != c.valid {
+			t.Errorf("checkWordSize(%d) with %d-bit words = %v, want valid = %v", c.n, c.bits, err, c.valid)
+		}
+	}
+}
+
+func TestTransferOddLength16(t *testing.T) {
+	// fd -1 makes any ioctl fail with EBADF.
+	dev := &Device{path: "test", fd: -1, bits: 16}
+	buf := make([]byte, 3)
+	err := dev.Transfer(buf, buf)
+	if err == nil || errors.Is(err, unix.EBADF) {
+		t.Errorf("Transfer of 3 bytes with 16-bit words = %v, want rejection before the ioctl", err)
+	}
+	_, err = dev.Write(buf)
+	if err == nil || errors.Is(err, unix.EBADF) {
+		t.Errorf("Write of 3 bytes with 16-bit words = %v, want rejection before the ioctl", err)
+	}
+}
+
+func TestNewTransferZeroSpeed(t *testing.T) {
+	buf := make([]byte, 2)
+	dev := &Device{}
+	tr := dev.newTransfer(buf, buf, len(buf))
+	if tr.speed_hz != 0 {
+		t.Errorf("zero-speed device: speed_hz = %d, want 0 (kernel default)", tr.speed_hz)
+	}
+	dev.speed = 1000000
+	tr = dev.newTransfer(buf, buf, len(buf))
+	if tr.speed_hz != 1000000 {
+		t.Errorf("speed_hz = %d, want 1000000", tr.speed_hz)
+	}
+}
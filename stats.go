@@ -0,0 +1,86 @@
+package spi
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// Stats holds cumulative counts of the transfers made on a device.
+type Stats struct {
+	// Transfers is the number of successful transfers.
+	// Each segment of a multi-message transfer counts separately.
+	Transfers uint64
+
+	// BytesSent and BytesReceived count the bytes in successful transfers.
+	// Half-duplex transfers count only in the direction used.
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// Errors counts failed transfer ioctls by errno.
+	Errors map[unix.Errno]uint64
+}
+
+// stats holds the counters reported by Stats.
+// The uint64 fields are updated atomically and must come first,
+// so that they are 64-bit aligned on 32-bit platforms.
+type stats struct {
+	transfers     uint64
+	bytesSent     uint64
+	bytesReceived uint64
+
+	mu     sync.Mutex
+	errors map[unix.Errno]uint64
+}
+
+// Stats returns the device's transfer statistics.
+// It can be called while transfers are in progress on other goroutines.
+func (dev *Device) Stats() Stats {
+	s := &dev.stats
+	st := Stats{
+		Transfers:     atomic.LoadUint64(&s.transfers),
+		BytesSent:     atomic.LoadUint64(&s.bytesSent),
+		BytesReceived: atomic.LoadUint64(&s.bytesReceived),
+		Errors:        make(map[unix.Errno]uint64),
+	}
+	s.mu.Lock()
+	for errno, n := range s.errors {
+		st.Errors[errno] = n
+	}
+	s.mu.Unlock()
+	return st
+}
+
+// record updates the counters after the transfers in tr were submitted.
+func (s *stats) record(tr []spi_ioc_transfer, err error) {
+	if err != nil {
+		var errno unix.Errno
+		errors.As(err, &errno)
+		s.mu.Lock()
+		if s.errors == nil {
+			s.errors = make(map[unix.Errno]uint64)
+		}
+		s.errors[errno]++
+		s.mu.Unlock()
+		return
+	}
+	sent, received := byteCounts(tr)
+	atomic.AddUint64(&s.transfers, uint64(len(tr)))
+	atomic.AddUint64(&s.bytesSent, sent)
+	atomic.AddUint64(&s.bytesReceived, received)
+}
+
+// byteCounts returns the number of bytes sent and received by the transfers in tr.
+func byteCounts(tr []spi_ioc_transfer) (sent, received uint64) {
+	for i := range tr {
+		if tr[i].tx_buf != 0 {
+			sent += uint64(tr[i].len)
+		}
+		if tr[i].rx_buf != 0 {
+			received += uint64(tr[i].len)
+		}
+	}
+	return sent, received
+}
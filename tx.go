@@ -0,0 +1,80 @@
+package spi
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Tx performs transfers within a Transaction.
+type Tx struct {
+	dev *Device
+}
+
+// Transaction asserts the custom chip select (if any), calls fn,
+// and deasserts the chip select when fn returns, even if it fails.
+// Transfers made through the Tx do not assert or deassert
+// the custom chip select themselves, so a multi-step sequence
+// stays within a single chip select assertion.
+// The device is locked for the duration, so fn must not use it directly,
+// and the Tx must not be used after fn returns.
+//
+// A hardware chip select is not affected: it is still asserted
+// and deasserted by the kernel around each transfer.
+func (dev *Device) Transaction(fn func(tx *Tx) error) error {
+	dev.mu.Lock()
+	defer dev.mu.Unlock()
+	if dev.autoCS() {
+		dev.selectCS()
+		defer dev.deselectCS()
+	}
+	return fn(&Tx{dev: dev})
+}
+
+// Transfer performs an SPI transfer operation (send and receive).
+func (tx *Tx) Transfer(snd, rcv []byte) error {
+	if len(snd) != len(rcv) {
+		return fmt.Errorf("transfer buffers must be the same length (snd = %d, rcv = %d)", len(snd), len(rcv))
+	}
+	return tx.transfer(snd, rcv, len(snd))
+}
+
+// Write performs a transmit-only SPI transfer.
+func (tx *Tx) Write(buf []byte) (int, error) {
+	err := tx.transfer(buf, nil, len(buf))
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+// Read performs a receive-only SPI transfer, filling buf with the received bytes.
+func (tx *Tx) Read(buf []byte) (int, error) {
+	err := tx.transfer(nil, buf, len(buf))
+	if err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+func (tx *Tx) transfer(snd, rcv []byte, n int) error {
+	if n == 0 {
+		return nil
+	}
+	err := checkLength(n)
+	if err != nil {
+		return err
+	}
+	dev := tx.dev
+	err = dev.checkWordSize(n)
+	if err != nil {
+		return err
+	}
+	tr := dev.newTransfer(snd, rcv, n)
+	err = dev.submit([]spi_ioc_transfer{tr})
+	runtime.KeepAlive(snd)
+	runtime.KeepAlive(rcv)
+	if err != nil {
+		return err
+	}
+	return dev.completed(snd, rcv)
+}
@@ -0,0 +1,101 @@
+package spi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// nativeEndian is the byte order of the CPU,
+// which spidev uses for words larger than 8 bits.
+var nativeEndian = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// wordBytes returns the number of bytes occupied by each word in a transfer:
+// words of 9 to 16 bits occupy 2 bytes, and larger words 4 bytes.
+// The caller must hold dev.mu.
+func (dev *Device) wordBytes() int {
+	bits := dev.bitsPerWord()
+	switch {
+	case bits > 16:
+		return 4
+	case bits > 8:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// transferPadded performs a transfer of n bytes of snd and rcv
+// (either of which may be nil) using temporary buffers
+// padded with zeros to a whole number of words.
+// The caller must hold dev.mu.
+func (dev *Device) transferPadded(snd, rcv []byte, n int) error {
+	size := dev.wordBytes()
+	padded := n + size - n%size
+	var s, r []byte
+	if snd != nil {
+		s = make([]byte, padded)
+		copy(s, snd[:n])
+	}
+	if rcv != nil {
+		r = make([]byte, padded)
+	}
+	tr := dev.newTransfer(s, r, padded)
+	err := dev.message([]spi_ioc_transfer{tr})
+	runtime.KeepAlive(s)
+	runtime.KeepAlive(r)
+	if err != nil {
+		return err
+	}
+	copy(rcv, r)
+	return dev.completed(snd, rcv)
+}
+
+// Transfer16 performs an SPI transfer operation of 16-bit words,
+// and returns the words received.
+// With a word size of 9 to 16 bits, each value is one SPI word,
+// sent in the device's bit order. With 8-bit words, each value
+// is sent as two bytes, most significant first, or least significant
+// first if LSBFirst is set, so that the bits of the value are on the wire
+// in the same order either way.
+func (dev *Device) Transfer16(snd []uint16) ([]uint16, error) {
+	dev.mu.Lock()
+	bits := dev.bitsPerWord()
+	dev.mu.Unlock()
+	var order binary.ByteOrder
+	switch {
+	case bits == 8:
+		lsb, err := dev.LSBFirst()
+		if err != nil {
+			return nil, err
+		}
+		order = binary.BigEndian
+		if lsb {
+			order = binary.LittleEndian
+		}
+	case 8 < bits && bits <= 16:
+		order = nativeEndian
+	default:
+		return nil, fmt.Errorf("Transfer16 cannot be used with %d-bit words", bits)
+	}
+	buf := make([]byte, 2*len(snd))
+	for i, v := range snd {
+		order.PutUint16(buf[2*i:], v)
+	}
+	err := dev.TransferInPlace(buf)
+	if err != nil {
+		return nil, err
+	}
+	rcv := make([]uint16, len(snd))
+	for i := range rcv {
+		rcv[i] = order.Uint16(buf[2*i:])
+	}
+	return rcv, nil
+}